@@ -0,0 +1,891 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/mcdb"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/justtaldevelops/worldcompute/javaedition"
+	"github.com/justtaldevelops/worldcompute/structure"
+	"github.com/justtaldevelops/worldcompute/worlddiff"
+	"github.com/justtaldevelops/worldcompute/worldrenderer"
+	"github.com/justtaldevelops/worldcompute/worldsearch"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runCommand runs the command line command passed, with the first argument being the name of the command.
+func runCommand(args []string) error {
+	switch args[0] {
+	case "view":
+		return viewCommand(args[1:])
+	case "search":
+		return searchCommand(args[1:])
+	case "export":
+		return exportCommand(args[1:])
+	case "import":
+		return importCommand(args[1:])
+	case "convert":
+		return convertCommand(args[1:])
+	case "diff":
+		return diffCommand(args[1:])
+	case "archive":
+		return archiveCommand(args[1:])
+	case "check":
+		return checkCommand(args[1:])
+	case "trim":
+		return trimCommand(args[1:])
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// viewCommand opens a saved world in the renderer. Usage:
+// view [-dimension id] [-cache size] [-timelapse interval] [-pack path] [-colours file] <world folder>
+func viewCommand(args []string) error {
+	set := flag.NewFlagSet("view", flag.ExitOnError)
+	dimensionID := set.Int("dimension", 0, "the ID of the dimension to view: 0 for the overworld, 1 for the nether and 2 for the end")
+	cacheSize := set.Int("cache", 4096, "the maximum amount of chunks kept in memory")
+	timeLapse := set.Duration("timelapse", time.Second*5, "the interval between the frames of time-lapses")
+	pack := set.String("pack", "", "a resource pack folder or archive to derive the colours of blocks from")
+	colours := set.String("colours", "", "a JSON file overriding the colours of blocks")
+	_ = set.Parse(args)
+	if set.NArg() != 1 {
+		return fmt.Errorf("usage: view [-dimension id] [-cache size] [-timelapse interval] [-pack path] [-colours file] <world folder>")
+	}
+
+	dim, ok := world.DimensionByID(*dimensionID)
+	if !ok {
+		return fmt.Errorf("unknown dimension %v", *dimensionID)
+	}
+	prov, err := mcdb.Open(set.Arg(0), dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	src, err := worldrenderer.NewProviderSource(prov, *cacheSize)
+	if err != nil {
+		return err
+	}
+	var s world.Settings
+	prov.Settings(&s)
+
+	r := worldrenderer.NewRenderer(4, 6.5, mgl64.Vec2{float64(s.Spawn.X()), float64(s.Spawn.Z())}, dim, src)
+	r.SetNetherScale(float64(s.NetherScale))
+	r.SetTimeLapseInterval(*timeLapse)
+	if *pack != "" || *colours != "" {
+		p, err := loadPalette(*pack, *colours)
+		if err != nil {
+			return err
+		}
+		r.SetPalette(p)
+	}
+	for _, other := range []world.Dimension{world.Overworld, world.Nether, world.End} {
+		if other == dim {
+			continue
+		}
+		// The other dimensions are opened too, so that the view may jump between the overworld and the nether.
+		otherProv, err := mcdb.Open(set.Arg(0), other)
+		if err != nil {
+			return fmt.Errorf("error opening world: %w", err)
+		}
+		defer otherProv.Close()
+
+		otherSrc, err := worldrenderer.NewProviderSource(otherProv, *cacheSize)
+		if err != nil {
+			return err
+		}
+		r.SetSource(other, otherSrc)
+	}
+	return runRenderer(r)
+}
+
+// loadPalette loads a worldrenderer.Palette from the resource pack and colour overrides file passed. Either of them
+// may be empty.
+func loadPalette(pack, overrides string) (*worldrenderer.Palette, error) {
+	p := worldrenderer.NewPalette()
+	if pack != "" {
+		if err := p.LoadResourcePack(pack); err != nil {
+			return nil, err
+		}
+	}
+	if overrides != "" {
+		if err := p.LoadOverrides(overrides); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// searchCommand searches a saved world for blocks matching a query and prints their positions. Usage:
+// search [-dimension id] [-limit n] <world folder> <query>
+func searchCommand(args []string) error {
+	set := flag.NewFlagSet("search", flag.ExitOnError)
+	dimensionID := set.Int("dimension", 0, "the ID of the dimension to search: 0 for the overworld, 1 for the nether and 2 for the end")
+	limit := set.Int("limit", 0, "the maximum amount of positions to print, or 0 to print all positions")
+	_ = set.Parse(args)
+	if set.NArg() < 2 {
+		return fmt.Errorf("usage: search [-dimension id] [-limit n] <world folder> <query>")
+	}
+	q, err := worldsearch.ParseQuery(strings.Join(set.Args()[1:], " "))
+	if err != nil {
+		return err
+	}
+
+	dim, ok := world.DimensionByID(*dimensionID)
+	if !ok {
+		return fmt.Errorf("unknown dimension %v", *dimensionID)
+	}
+	prov, err := mcdb.Open(set.Arg(0), dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	src, err := worldrenderer.NewProviderSource(prov, 64)
+	if err != nil {
+		return err
+	}
+	found := worldsearch.NewSearcher(q).Search(src)
+	for i, pos := range found {
+		if *limit > 0 && i == *limit {
+			break
+		}
+		fmt.Printf("%v %v %v\n", pos.X(), pos.Y(), pos.Z())
+	}
+	fmt.Printf("found %v blocks matching %v\n", len(found), q)
+	return nil
+}
+
+// exportCommand exports the cuboid between two corners of a saved world to a .mcstructure file, or to a Sponge
+// schematic if the file has the .schem extension. Usage:
+// export [-dimension id] [-version 2|3] -from x,y,z -to x,y,z <world folder> <file>
+func exportCommand(args []string) error {
+	set := flag.NewFlagSet("export", flag.ExitOnError)
+	dimensionID := set.Int("dimension", 0, "the ID of the dimension to export from: 0 for the overworld, 1 for the nether and 2 for the end")
+	version := set.Int("version", 2, "the version of the Sponge schematic format to use for .schem files: 2 or 3")
+	from := set.String("from", "", "the first corner of the cuboid to export, in the format x,y,z")
+	to := set.String("to", "", "the second corner of the cuboid to export, in the format x,y,z")
+	_ = set.Parse(args)
+	if set.NArg() != 2 || *from == "" || *to == "" {
+		return fmt.Errorf("usage: export [-dimension id] [-version 2|3] -from x,y,z -to x,y,z <world folder> <file>")
+	}
+	a, err := parseBlockPos(strings.Split(*from, ","), cube.Pos{})
+	if err != nil {
+		return err
+	}
+	b, err := parseBlockPos(strings.Split(*to, ","), cube.Pos{})
+	if err != nil {
+		return err
+	}
+
+	dim, ok := world.DimensionByID(*dimensionID)
+	if !ok {
+		return fmt.Errorf("unknown dimension %v", *dimensionID)
+	}
+	prov, err := mcdb.Open(set.Arg(0), dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	src, err := worldrenderer.NewProviderSource(prov, 64)
+	if err != nil {
+		return err
+	}
+	s := structure.Read(src, a, b)
+	unmapped, err := writeExport(set.Arg(1), s, *version)
+	if err != nil {
+		return err
+	}
+	size := s.Size()
+	fmt.Printf("exported %vx%vx%v structure to %v\n", size[0], size[1], size[2], set.Arg(1))
+	for _, line := range unmappedReport(unmapped, "Java Edition") {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// importCommand places a .mcstructure file in a saved world. Chunks that the structure covers but that do not exist
+// in the world yet are created. Usage:
+// import [-dimension id] -at x,y,z [-rotate n] [-mirror x|z] <world folder> <file>
+func importCommand(args []string) error {
+	set := flag.NewFlagSet("import", flag.ExitOnError)
+	dimensionID := set.Int("dimension", 0, "the ID of the dimension to import into: 0 for the overworld, 1 for the nether and 2 for the end")
+	at := set.String("at", "", "the position of the lowest corner of the structure, in the format x,y,z")
+	rotate := set.Int("rotate", 0, "the amount of times to rotate the structure clockwise by 90 degrees")
+	mirror := set.String("mirror", "", "the axis to mirror the structure along: x or z")
+	_ = set.Parse(args)
+	if set.NArg() != 2 || *at == "" {
+		return fmt.Errorf("usage: import [-dimension id] -at x,y,z [-rotate n] [-mirror x|z] <world folder> <file>")
+	}
+	if mcdb.IsArchive(set.Arg(0)) {
+		return fmt.Errorf("cannot import into a .mcworld archive, as changes are not written back to it")
+	}
+	pos, err := parseBlockPos(strings.Split(*at, ","), cube.Pos{})
+	if err != nil {
+		return err
+	}
+	s, unknown, err := readStructure(set.Arg(1), *rotate, *mirror)
+	if err != nil {
+		return err
+	}
+
+	dim, ok := world.DimensionByID(*dimensionID)
+	if !ok {
+		return fmt.Errorf("unknown dimension %v", *dimensionID)
+	}
+	prov, err := mcdb.New(set.Arg(0), dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	for _, chunkPos := range s.Chunks(pos) {
+		c, exists, err := prov.LoadChunk(chunkPos)
+		if err != nil {
+			return fmt.Errorf("error loading chunk %v: %w", chunkPos, err)
+		}
+		var blockEntities []map[string]interface{}
+		if exists {
+			if blockEntities, err = prov.LoadBlockNBT(chunkPos); err != nil {
+				return fmt.Errorf("error loading block entities of chunk %v: %w", chunkPos, err)
+			}
+		} else {
+			c = chunk.New(air, dim.Range())
+		}
+		blockEntities = s.PlaceChunk(pos, chunkPos, c, blockEntities)
+		if err := prov.SaveChunk(chunkPos, c); err != nil {
+			return fmt.Errorf("error saving chunk %v: %w", chunkPos, err)
+		}
+		if err := prov.SaveBlockNBT(chunkPos, blockEntities); err != nil {
+			return fmt.Errorf("error saving block entities of chunk %v: %w", chunkPos, err)
+		}
+	}
+	size := s.Size()
+	fmt.Printf("imported %vx%vx%v structure at %v, %v, %v\n", size[0], size[1], size[2], pos.X(), pos.Y(), pos.Z())
+	if len(unknown) > 0 {
+		fmt.Printf("skipped unknown blocks: %v\n", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// convertCommand converts a world between the formats of Bedrock Edition and the anvil format of Java Edition. A
+// saved world is converted to a region folder for every dimension with -to anvil, and a Java world is converted to a
+// saved world with -to mcdb. Usage:
+// convert -to anvil|mcdb [-dimension id] <world folder> <output folder>
+func convertCommand(args []string) error {
+	set := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := set.String("to", "", "the format to convert the world to: anvil to convert a saved world to Java Edition, or mcdb to convert a Java world to a saved world")
+	dimensionID := set.Int("dimension", -1, "the ID of the dimension to convert: 0 for the overworld, 1 for the nether, 2 for the end and -1 for all dimensions")
+	_ = set.Parse(args)
+	if set.NArg() != 2 || *to == "" {
+		return fmt.Errorf("usage: convert -to anvil|mcdb [-dimension id] <world folder> <output folder>")
+	}
+	dims := []world.Dimension{world.Overworld, world.Nether, world.End}
+	if *dimensionID != -1 {
+		dim, ok := world.DimensionByID(*dimensionID)
+		if !ok {
+			return fmt.Errorf("unknown dimension %v", *dimensionID)
+		}
+		dims = []world.Dimension{dim}
+	}
+
+	var report []string
+	switch strings.ToLower(*to) {
+	case "anvil":
+		conv := javaedition.NewConverter()
+		for _, dim := range dims {
+			if err := convertToAnvil(set.Arg(0), set.Arg(1), dim, conv); err != nil {
+				return err
+			}
+		}
+		report = unmappedReport(conv.Unmapped(), "Java Edition")
+	case "mcdb":
+		conv := javaedition.NewBedrockConverter()
+		for _, dim := range dims {
+			if err := convertToMCDB(set.Arg(0), set.Arg(1), dim, conv); err != nil {
+				return err
+			}
+		}
+		if err := convertLevel(set.Arg(0), set.Arg(1)); err != nil {
+			return err
+		}
+		report = unmappedReport(conv.Unmapped(), "Bedrock Edition")
+		if n := conv.Incomplete(); n > 0 {
+			report = append([]string{fmt.Sprintf("skipped %v chunks that were not fully generated", n)}, report...)
+		}
+	default:
+		return fmt.Errorf("cannot convert to format %q, must be anvil or mcdb", *to)
+	}
+	for _, line := range report {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// anvilFolders holds the folders within a Java world that the region files of every dimension are stored in.
+var anvilFolders = map[world.Dimension]string{
+	world.Overworld: "region",
+	world.Nether:    filepath.Join("DIM-1", "region"),
+	world.End:       filepath.Join("DIM1", "region"),
+}
+
+// convertToAnvil converts the chunks of a dimension of the saved world in the folder passed to region files in the
+// output folder, using the javaedition.Converter passed. Chunks are read one region at a time, so that only the chunks
+// of a single region are held in memory.
+func convertToAnvil(folder, output string, dim world.Dimension, conv *javaedition.Converter) error {
+	prov, err := mcdb.Open(folder, dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	positions, err := prov.Positions()
+	if err != nil {
+		return err
+	}
+	regions := make(map[[2]int32][]world.ChunkPos)
+	for _, pos := range positions {
+		regionPos := javaedition.RegionPos(pos)
+		regions[regionPos] = append(regions[regionPos], pos)
+	}
+	if len(regions) == 0 {
+		return nil
+	}
+	dir := filepath.Join(output, anvilFolders[dim])
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("error creating region folder: %w", err)
+	}
+
+	converted := 0
+	for regionPos, chunks := range regions {
+		var region javaedition.Region
+		for _, pos := range chunks {
+			c, exists, err := prov.LoadChunk(pos)
+			if err != nil {
+				return fmt.Errorf("error loading chunk %v: %w", pos, err)
+			} else if !exists {
+				continue
+			}
+			blockEntities, err := prov.LoadBlockNBT(pos)
+			if err != nil {
+				return fmt.Errorf("error loading block entities of chunk %v: %w", pos, err)
+			}
+			data, err := conv.EncodeChunk(pos, c, blockEntities)
+			if err != nil {
+				return err
+			}
+			if err := region.SetChunk(pos, data); err != nil {
+				return err
+			}
+		}
+		if err := writeRegion(filepath.Join(dir, javaedition.RegionFileName(regionPos)), &region); err != nil {
+			return err
+		}
+		converted += region.Len()
+	}
+	fmt.Printf("converted %v chunks of %v in %v regions\n", converted, dim, len(regions))
+	return nil
+}
+
+// convertToMCDB converts the region files of a dimension of the Java world in the folder passed to the saved world in
+// the output folder, using the javaedition.BedrockConverter passed. Region files are read one at a time.
+func convertToMCDB(folder, output string, dim world.Dimension, conv *javaedition.BedrockConverter) error {
+	files, err := filepath.Glob(filepath.Join(folder, anvilFolders[dim], "r.*.*.mca"))
+	if err != nil {
+		return fmt.Errorf("error listing region files: %w", err)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	prov, err := mcdb.New(output, dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	converted := 0
+	for _, file := range files {
+		var regionPos [2]int32
+		if _, err := fmt.Sscanf(filepath.Base(file), "r.%d.%d.mca", &regionPos[0], &regionPos[1]); err != nil {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("error reading region file: %w", err)
+		}
+		region, err := javaedition.ReadRegion(data)
+		if err != nil {
+			return fmt.Errorf("error reading region file %v: %w", filepath.Base(file), err)
+		}
+		for _, pos := range region.Positions(regionPos) {
+			chunkData, _, err := region.Chunk(pos)
+			if err != nil {
+				return err
+			}
+			chunkPos, c, blockEntities, err := conv.DecodeChunk(chunkData, dim.Range())
+			if err != nil {
+				return err
+			} else if c == nil {
+				// The chunk was not fully generated.
+				continue
+			}
+			if err := prov.SaveChunk(chunkPos, c); err != nil {
+				return fmt.Errorf("error saving chunk %v: %w", chunkPos, err)
+			}
+			if err := prov.SaveBlockNBT(chunkPos, blockEntities); err != nil {
+				return fmt.Errorf("error saving block entities of chunk %v: %w", chunkPos, err)
+			}
+			converted++
+		}
+	}
+	fmt.Printf("converted %v chunks of %v in %v regions\n", converted, dim, len(files))
+	return nil
+}
+
+// convertLevel carries the name and spawn of the Java world in the folder passed over to the saved world in the
+// output folder. Nothing is done if the Java world has no level.dat.
+func convertLevel(folder, output string) error {
+	f, err := os.Open(filepath.Join(folder, "level.dat"))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error opening level.dat: %w", err)
+	}
+	defer f.Close()
+	level, err := javaedition.ReadLevel(f)
+	if err != nil {
+		return err
+	}
+
+	prov, err := mcdb.New(output, world.Overworld)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	var s world.Settings
+	prov.Settings(&s)
+	s.Name, s.Spawn = level.Name, level.Spawn
+	prov.SaveSettings(&s)
+	return prov.Close()
+}
+
+// writeRegion writes the javaedition.Region passed to the region file with the name passed.
+func writeRegion(name string, region *javaedition.Region) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating region file: %w", err)
+	}
+	if _, err := region.WriteTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// diffCommand compares two saved worlds chunk by chunk and prints the chunks that differ together with the amount of
+// blocks added, removed and changed in them. Usage:
+// diff [-dimension id] [-blocks] [-limit n] [-image file] <old world folder> <new world folder>
+func diffCommand(args []string) error {
+	set := flag.NewFlagSet("diff", flag.ExitOnError)
+	dimensionID := set.Int("dimension", 0, "the ID of the dimension to compare: 0 for the overworld, 1 for the nether and 2 for the end")
+	blocks := set.Bool("blocks", false, "print every block that differs instead of the chunks")
+	limit := set.Int("limit", 0, "the maximum amount of lines to print, or 0 to print all lines")
+	imageFile := set.String("image", "", "a PNG file to render the differences to, top-down")
+	_ = set.Parse(args)
+	if set.NArg() != 2 {
+		return fmt.Errorf("usage: diff [-dimension id] [-blocks] [-limit n] [-image file] <old world folder> <new world folder>")
+	}
+	for _, folder := range set.Args() {
+		if _, err := os.Stat(filepath.Join(folder, "db")); err != nil && !mcdb.IsArchive(folder) {
+			return fmt.Errorf("%v is not a saved world: %w", folder, err)
+		}
+	}
+
+	dim, ok := world.DimensionByID(*dimensionID)
+	if !ok {
+		return fmt.Errorf("unknown dimension %v", *dimensionID)
+	}
+	a, err := mcdb.Open(set.Arg(0), dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer a.Close()
+	b, err := mcdb.Open(set.Arg(1), dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer b.Close()
+
+	diff, err := worlddiff.NewDiffer(dim.Range(), *blocks).Diff(a, b)
+	if err != nil {
+		return err
+	}
+	lines := 0
+	for _, c := range diff.Chunks {
+		if *blocks {
+			for _, block := range c.Blocks {
+				if *limit > 0 && lines == *limit {
+					break
+				}
+				fmt.Println(block)
+				lines++
+			}
+			continue
+		}
+		if *limit > 0 && lines == *limit {
+			break
+		}
+		fmt.Printf("chunk %v %v: %v added, %v removed, %v changed\n", c.Pos.X(), c.Pos.Z(), c.Added, c.Removed, c.Changed)
+		lines++
+	}
+	fmt.Printf("%v of %v chunks differ: %v blocks added, %v removed, %v changed\n", len(diff.Chunks), diff.Compared, diff.Added(), diff.Removed(), diff.Changed())
+
+	if *imageFile != "" {
+		img, origin := diff.Image()
+		if img == nil {
+			return fmt.Errorf("no chunks to render")
+		}
+		f, err := os.Create(*imageFile)
+		if err != nil {
+			return fmt.Errorf("error creating image: %w", err)
+		}
+		defer f.Close()
+		if err := png.Encode(f, img); err != nil {
+			return fmt.Errorf("error encoding image: %w", err)
+		}
+		fmt.Printf("rendered differences to %v, with its top left corner at x=%v z=%v\n", *imageFile, origin[0], origin[1])
+	}
+	return nil
+}
+
+// archiveCommand writes a saved world to a .mcworld archive, which may be opened directly by Minecraft. Usage:
+// archive <world folder> <file>
+func archiveCommand(args []string) error {
+	set := flag.NewFlagSet("archive", flag.ExitOnError)
+	_ = set.Parse(args)
+	if set.NArg() != 2 {
+		return fmt.Errorf("usage: archive <world folder> <file>")
+	}
+	folder, file := set.Arg(0), set.Arg(1)
+	if _, err := os.Stat(filepath.Join(folder, "level.dat")); err != nil {
+		return fmt.Errorf("%v is not a saved world: %w", folder, err)
+	}
+	if !strings.EqualFold(filepath.Ext(file), mcdb.ArchiveExtension) {
+		file += mcdb.ArchiveExtension
+	}
+	if err := mcdb.WriteArchive(folder, file); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %v to %v\n", folder, file)
+	return nil
+}
+
+// checkCommand checks every entry of a saved world for problems that could stop Minecraft from loading it, and prints
+// them. With -repair, broken entries are deleted or rebuilt, and a broken level.dat is replaced. Usage:
+// check [-repair] <world folder>
+func checkCommand(args []string) error {
+	set := flag.NewFlagSet("check", flag.ExitOnError)
+	repair := set.Bool("repair", false, "delete or rebuild the broken entries found")
+	_ = set.Parse(args)
+	if set.NArg() != 1 {
+		return fmt.Errorf("usage: check [-repair] <world folder>")
+	}
+	folder := set.Arg(0)
+	if mcdb.IsArchive(folder) {
+		if *repair {
+			return fmt.Errorf("cannot repair a .mcworld archive, as changes are not written back to it")
+		}
+	} else if _, err := os.Stat(filepath.Join(folder, "db")); err != nil {
+		return fmt.Errorf("%v is not a saved world: %w", folder, err)
+	}
+
+	levelProblem := false
+	if !mcdb.IsArchive(folder) {
+		if err := mcdb.CheckLevelDat(folder); err != nil {
+			levelProblem = true
+			fmt.Printf("level.dat: %v\n", err)
+			if !*repair {
+				return fmt.Errorf("the world cannot be opened with a broken level.dat: run check with -repair to replace it")
+			}
+			// The broken level.dat is kept aside, and the provider writes a default one in its place when closed.
+			if err := os.Rename(filepath.Join(folder, "level.dat"), filepath.Join(folder, "level.dat.broken")); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("error moving broken level.dat: %w", err)
+			}
+		}
+	}
+
+	// The world is only opened for writing when repairing it. Even then, the level.dat is only written if it was
+	// replaced, as it is only written by the provider if it was missing or its settings were saved.
+	open := mcdb.Open
+	if *repair {
+		open = mcdb.New
+	}
+	prov, err := open(folder, world.Overworld)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+	if levelProblem {
+		// Keep the name of the world if it can still be found in levelname.txt.
+		if name, err := os.ReadFile(filepath.Join(folder, "levelname.txt")); err == nil && len(name) != 0 {
+			var s world.Settings
+			prov.Settings(&s)
+			s.Name = string(name)
+			prov.SaveSettings(&s)
+		}
+		fmt.Println("level.dat: replaced with a default level.dat")
+	}
+
+	problems, err := prov.Check()
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		if *repair {
+			fmt.Printf("%v (%v)\n", problem, problem.Repair())
+			continue
+		}
+		fmt.Println(problem)
+	}
+	if !*repair {
+		fmt.Printf("found %v broken entries\n", len(problems))
+		return nil
+	}
+	if err := prov.Repair(problems); err != nil {
+		return err
+	}
+	fmt.Printf("repaired %v broken entries\n", len(problems))
+	return nil
+}
+
+// trimCommand deletes the chunks of a saved world that are outside a bounding box or that hold only air, and compacts
+// the database afterwards to shrink the world. Usage:
+// trim [-dimension id] [-from x,z -to x,z] [-empty] <world folder>
+func trimCommand(args []string) error {
+	set := flag.NewFlagSet("trim", flag.ExitOnError)
+	dimensionID := set.Int("dimension", -1, "the ID of the dimension to trim: 0 for the overworld, 1 for the nether, 2 for the end and -1 for all dimensions")
+	from := set.String("from", "", "the first corner of the area of chunks to keep, in the format x,z")
+	to := set.String("to", "", "the second corner of the area of chunks to keep, in the format x,z")
+	empty := set.Bool("empty", false, "delete chunks that hold only air")
+	_ = set.Parse(args)
+	if set.NArg() != 1 || (*from == "") != (*to == "") || (*from == "" && !*empty) {
+		return fmt.Errorf("usage: trim [-dimension id] [-from x,z -to x,z] [-empty] <world folder>")
+	}
+	folder := set.Arg(0)
+	if mcdb.IsArchive(folder) {
+		return fmt.Errorf("cannot trim a .mcworld archive, as changes are not written back to it")
+	}
+	if _, err := os.Stat(filepath.Join(folder, "db")); err != nil {
+		return fmt.Errorf("%v is not a saved world: %w", folder, err)
+	}
+	dims := []world.Dimension{world.Overworld, world.Nether, world.End}
+	if *dimensionID != -1 {
+		dim, ok := world.DimensionByID(*dimensionID)
+		if !ok {
+			return fmt.Errorf("unknown dimension %v", *dimensionID)
+		}
+		dims = []world.Dimension{dim}
+	}
+
+	// The area is kept in chunk coordinates. Chunks partially inside the area are kept.
+	var minChunk, maxChunk world.ChunkPos
+	if *from != "" {
+		a, err := parseColumn(*from)
+		if err != nil {
+			return err
+		}
+		b, err := parseColumn(*to)
+		if err != nil {
+			return err
+		}
+		minChunk = world.ChunkPos{int32(a[0]) >> 4, int32(a[1]) >> 4}
+		maxChunk = world.ChunkPos{int32(b[0]) >> 4, int32(b[1]) >> 4}
+		if minChunk[0] > maxChunk[0] {
+			minChunk[0], maxChunk[0] = maxChunk[0], minChunk[0]
+		}
+		if minChunk[1] > maxChunk[1] {
+			minChunk[1], maxChunk[1] = maxChunk[1], minChunk[1]
+		}
+	}
+
+	// A provider for the overworld is kept open while trimming, so that all dimensions share the same database and
+	// it is only compacted once.
+	prov, err := mcdb.New(folder, world.Overworld)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+	for _, dim := range dims {
+		if err := trimDimension(folder, dim, *from != "", minChunk, maxChunk, *empty); err != nil {
+			return err
+		}
+	}
+	fmt.Println("compacting database...")
+	return prov.Compact()
+}
+
+// trimDimension deletes the chunks of a dimension of the saved world in the folder passed that are outside the area
+// between minChunk and maxChunk, if area is true, or that hold only air, if empty is true.
+func trimDimension(folder string, dim world.Dimension, area bool, minChunk, maxChunk world.ChunkPos, empty bool) error {
+	prov, err := mcdb.New(folder, dim)
+	if err != nil {
+		return fmt.Errorf("error opening world: %w", err)
+	}
+	defer prov.Close()
+
+	positions, err := prov.Positions()
+	if err != nil {
+		return err
+	}
+	deleted := 0
+	for _, pos := range positions {
+		remove := area && (pos[0] < minChunk[0] || pos[0] > maxChunk[0] || pos[1] < minChunk[1] || pos[1] > maxChunk[1])
+		if !remove && empty {
+			if remove, err = prov.ChunkEmpty(pos); err != nil {
+				return err
+			}
+		}
+		if !remove {
+			continue
+		}
+		if err := prov.DeleteChunk(pos); err != nil {
+			return err
+		}
+		deleted++
+	}
+	fmt.Printf("deleted %v of %v chunks in %v\n", deleted, len(positions), dim)
+	return nil
+}
+
+// parseBlockPos parses a block position from the x, y and z coordinates passed. If no coordinates are passed, the
+// fallback position is returned.
+func parseBlockPos(coords []string, fallback cube.Pos) (cube.Pos, error) {
+	if len(coords) == 0 {
+		return fallback, nil
+	}
+	if len(coords) != 3 {
+		return cube.Pos{}, fmt.Errorf("position must have three coordinates, got %v", len(coords))
+	}
+	var pos cube.Pos
+	for i, coord := range coords {
+		v, err := strconv.Atoi(strings.TrimSpace(coord))
+		if err != nil {
+			return cube.Pos{}, fmt.Errorf("invalid coordinate %q", coord)
+		}
+		pos[i] = v
+	}
+	return pos, nil
+}
+
+// parseColumn parses the X and Z coordinates of a block column in the format x,z.
+func parseColumn(s string) ([2]int, error) {
+	coords := strings.Split(s, ",")
+	if len(coords) != 2 {
+		return [2]int{}, fmt.Errorf("column must have two coordinates, got %v", len(coords))
+	}
+	var column [2]int
+	for i, coord := range coords {
+		v, err := strconv.Atoi(strings.TrimSpace(coord))
+		if err != nil {
+			return [2]int{}, fmt.Errorf("invalid coordinate %q", coord)
+		}
+		column[i] = v
+	}
+	return column, nil
+}
+
+// readStructure reads a structure.Structure from the .mcstructure file with the name passed, then rotates it
+// clockwise the amount of times passed and mirrors it along the axis passed, which may be "x", "z" or empty. The names
+// of blocks in the structure that are not known are returned.
+func readStructure(name string, rotate int, mirror string) (*structure.Structure, []string, error) {
+	var axis cube.Axis
+	switch strings.ToLower(mirror) {
+	case "", "none":
+	case "x":
+		axis = cube.X
+	case "z":
+		axis = cube.Z
+	default:
+		return nil, nil, fmt.Errorf("cannot mirror along axis %q, must be x or z", mirror)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening structure file: %w", err)
+	}
+	defer f.Close()
+	s, unknown, err := structure.Decode(bufio.NewReader(f))
+	if err != nil {
+		return nil, nil, err
+	}
+	s = s.Rotate(rotate)
+	if axis != cube.Y {
+		s = s.Mirror(axis)
+	}
+	return s, unknown, nil
+}
+
+// writeExport writes the structure.Structure passed to the file with the name passed. Files with the .schem extension
+// are written as a Sponge schematic of the version passed, and all other files as a .mcstructure file. The blocks that
+// could not be converted to Java Edition for a schematic are returned.
+func writeExport(name string, s *structure.Structure, version int) (unmapped map[string]int, err error) {
+	if !strings.EqualFold(filepath.Ext(name), ".schem") {
+		return nil, writeStructure(name, s)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schematic file: %w", err)
+	}
+	if unmapped, err = javaedition.WriteSchematic(f, s, version); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return unmapped, f.Close()
+}
+
+// unmappedReport returns the lines of a report of the blocks that could not be converted to the edition passed, sorted
+// by the amount of blocks, most first. No lines are returned if all blocks were converted.
+func unmappedReport(unmapped map[string]int, edition string) []string {
+	if len(unmapped) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(unmapped))
+	for name := range unmapped {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if unmapped[names[i]] != unmapped[names[j]] {
+			return unmapped[names[i]] > unmapped[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	lines := []string{fmt.Sprintf("%v block types could not be converted to %v and were replaced with air:", len(names), edition)}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %v: %v", name, unmapped[name]))
+	}
+	return lines
+}
+
+// writeStructure writes the structure.Structure passed to a .mcstructure file with the name passed.
+func writeStructure(name string, s *structure.Structure) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating structure file: %w", err)
+	}
+	if err := s.Encode(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -124,6 +124,29 @@ func (chunk *Chunk) HighestBlock(x, z uint8) int16 {
 	return int16(chunk.r[0])
 }
 
+// HighestBlockBelow iterates from the sub chunk holding the Y value passed downwards to find the Y value of the
+// highest non-air block at an x and z that is at or below that Y value. The Y value passed is clamped to the range
+// of the chunk. If no blocks are present in the column below the Y value, the minimum of the range is returned.
+func (chunk *Chunk) HighestBlockBelow(x, z uint8, max int16) int16 {
+	if max > int16(chunk.r[1]) {
+		max = int16(chunk.r[1])
+	} else if max < int16(chunk.r[0]) {
+		return int16(chunk.r[0])
+	}
+	top := uint8(max & 0xf)
+	for index := chunk.SubIndex(max); index >= 0; index-- {
+		if sub := chunk.sub[index]; !sub.Empty() {
+			for y := int(top); y >= 0; y-- {
+				if rid := sub.storages[0].At(x, uint8(y), z); rid != chunk.air {
+					return int16(y) | chunk.SubY(index)
+				}
+			}
+		}
+		top = 15
+	}
+	return int16(chunk.r[0])
+}
+
 // Compact compacts the chunk as much as possible, getting rid of any sub chunks that are empty, and compacts
 // all storages in the sub chunks to occupy as little space as possible.
 // Compact should be called right before the chunk is saved in order to optimise the storage space.
@@ -133,6 +156,21 @@ func (chunk *Chunk) Compact() {
 	}
 }
 
+// Merge replaces the sub chunks of the chunk at the indices set in the bit mask passed with those of the newer chunk,
+// which must have the same range. If biomes is true, the biomes of these sub chunks are replaced too. The sub chunks
+// are not copied, so the newer chunk should no longer be modified afterwards.
+func (chunk *Chunk) Merge(newer *Chunk, subs uint64, biomes bool) {
+	for i := range chunk.sub {
+		if subs&(1<<i) == 0 || i >= len(newer.sub) {
+			continue
+		}
+		chunk.sub[i] = newer.sub[i]
+		if biomes {
+			chunk.biomes[i] = newer.biomes[i]
+		}
+	}
+}
+
 // SubChunk finds the correct SubChunk in the Chunk by a Y value.
 func (chunk *Chunk) SubChunk(y int16) *SubChunk {
 	return chunk.sub[chunk.SubIndex(y)]
@@ -9,14 +9,14 @@ import (
 // StateToRuntimeID must hold a function to convert a name and its state properties to a runtime ID.
 var StateToRuntimeID func(name string, properties map[string]interface{}) (runtimeID uint32, found bool)
 
-// NetworkDecode decodes the network serialised data passed into a Chunk if successful. If not, the chunk
-// returned is nil and the error non-nil.
+// NetworkDecode decodes the network serialised data in the buffer passed into a Chunk if successful. If not, the
+// chunk returned is nil and the error non-nil. Data following the chunk, such as its block entities, is left in the
+// buffer.
 // The sub chunk count passed must be that found in the LevelChunk packet.
 //noinspection GoUnusedExportedFunction
-func NetworkDecode(air uint32, data []byte, count int, oldBiomes bool, r cube.Range) (*Chunk, error) {
+func NetworkDecode(air uint32, buf *bytes.Buffer, count int, oldBiomes bool, r cube.Range) (*Chunk, error) {
 	var (
 		c   = New(air, r)
-		buf = bytes.NewBuffer(data)
 		err error
 	)
 	for i := 0; i < count; i++ {
@@ -160,6 +160,10 @@ func DecodeSubChunk(buf *bytes.Buffer, c *Chunk, index *byte, e Encoding) (*SubC
 			if err != nil {
 				return nil, err
 			}
+			if sub.storages[i] == nil {
+				// Only biome storages may point to the previous storage.
+				return nil, fmt.Errorf("block storage %v pointed to previous one", i)
+			}
 		}
 	}
 	return sub, nil
@@ -231,6 +235,9 @@ func decodePalettedStorage(buf *bytes.Buffer, e Encoding, pe paletteEncoding) (*
 	if blockSize == 0x7f {
 		return nil, nil
 	}
+	if !paletteSize(blockSize).valid() {
+		return nil, fmt.Errorf("invalid paletted storage block size %v", blockSize)
+	}
 
 	size := paletteSize(blockSize)
 	uint32Count := size.uint32s()
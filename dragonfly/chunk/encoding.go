@@ -89,6 +89,10 @@ func (diskEncoding) decodePalette(buf *bytes.Buffer, blockSize paletteSize, e pa
 		if err := binary.Read(buf, binary.LittleEndian, &paletteCount); err != nil {
 			return nil, fmt.Errorf("error reading palette entry count: %w", err)
 		}
+		if paletteCount > 4096 {
+			// A sub chunk has only 4096 blocks, so there can never be more values than that.
+			return nil, fmt.Errorf("invalid palette entry count %v", paletteCount)
+		}
 	}
 
 	var err error
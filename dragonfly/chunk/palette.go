@@ -96,6 +96,16 @@ func (palette *Palette) increaseSize() {
 	palette.size = sizes[offsets[palette.size]+1]
 }
 
+// valid checks if the paletteSize is one of the sizes that a Palette may have.
+func (p paletteSize) valid() bool {
+	for _, size := range sizes {
+		if p == size {
+			return true
+		}
+	}
+	return false
+}
+
 // padded returns true if the Palette size is 3, 5 or 6.
 func (p paletteSize) padded() bool {
 	return p == 3 || p == 5 || p == 6
@@ -90,6 +90,12 @@ func (storage *PalettedStorage) addNew(v uint32) int16 {
 	return index
 }
 
+// PaletteIndex returns the index in the Palette of the value at a given x, y and z. Together with the Palette,
+// this allows converting all values of the PalettedStorage by converting only the values in its Palette.
+func (storage *PalettedStorage) PaletteIndex(x, y, z byte) uint16 {
+	return storage.paletteIndex(x&15, y&15, z&15)
+}
+
 // paletteIndex looks up the Palette index at a given x, y and z value in the PalettedStorage. This palette
 // index is not the value at this offset, but merely an index in the Palette pointing to a value.
 func (storage *PalettedStorage) paletteIndex(x, y, z byte) uint16 {
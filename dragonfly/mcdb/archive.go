@@ -0,0 +1,143 @@
+package mcdb
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveExtension is the file extension of .mcworld archives, which are zip archives holding the files of a world.
+const ArchiveExtension = ".mcworld"
+
+// IsArchive checks if the path passed is a .mcworld archive, which New extracts before opening it.
+func IsArchive(file string) bool {
+	if !strings.EqualFold(filepath.Ext(file), ArchiveExtension) {
+		return false
+	}
+	info, err := os.Stat(file)
+	return err == nil && !info.IsDir()
+}
+
+// extractTemp extracts the .mcworld archive passed to a new temporary directory and returns it.
+func extractTemp(file string) (string, error) {
+	dir, err := os.MkdirTemp("", "worldcompute-mcworld-")
+	if err != nil {
+		return "", fmt.Errorf("error creating temporary directory: %w", err)
+	}
+	if err := ExtractArchive(file, dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// ExtractArchive extracts the .mcworld archive passed to the directory passed. Archives that hold the world in a
+// single folder, rather than at the root of the archive, are extracted without that folder. Files that would be
+// extracted outside the directory result in an error.
+func ExtractArchive(file, dir string) error {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("error opening archive: %w", err)
+	}
+	defer r.Close()
+
+	prefix := archivePrefix(r.File)
+	for _, f := range r.File {
+		name := strings.TrimPrefix(f.Name, prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if rel, err := filepath.Rel(dir, dst); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("archive file %q is outside the archive", f.Name)
+		}
+		if err := extractFile(f, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivePrefix returns the name of the folder that holds the level.dat of the world in the archive files passed,
+// including a trailing slash, or an empty string if the level.dat is at the root of the archive.
+func archivePrefix(files []*zip.File) string {
+	prefix := ""
+	for _, f := range files {
+		if f.Name == "level.dat" {
+			return ""
+		}
+		if path.Base(f.Name) == "level.dat" && strings.Count(f.Name, "/") == 1 {
+			prefix = path.Dir(f.Name) + "/"
+		}
+	}
+	return prefix
+}
+
+// extractFile extracts the archive file passed to the path dst, creating its directory if needed.
+func extractFile(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	src, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("error opening archive file %v: %w", f.Name, err)
+	}
+	defer src.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("error extracting archive file %v: %w", f.Name, err)
+	}
+	return out.Close()
+}
+
+// WriteArchive writes all files in the world directory passed to a .mcworld archive at the path passed. The world
+// must not be open, so that the leveldb database and level.dat are completely written.
+func WriteArchive(dir, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("error creating archive: %w", err)
+	}
+	w := zip.NewWriter(f)
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name, header.Method = filepath.ToSlash(rel), zip.Deflate
+		dst, err := w.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		src, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(dst, src)
+		return err
+	})
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	return f.Close()
+}
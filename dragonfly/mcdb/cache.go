@@ -1,41 +1,95 @@
 package mcdb
 
 import (
+	"fmt"
 	"github.com/df-mc/goleveldb/leveldb"
+	"path/filepath"
 	"sync"
 )
 
 var (
-	// cache holds a map of path => *leveldb.DB. It is used to cache providers that are re-used for different dimensions
-	// with the same underlying levelDB database.
-	cache sync.Map
-	// refc is a map that serves as reference counter for the *leveldb.DB instances stored in the cache variable above. A
-	// *leveldb.DB instance is removed from the cache is the ref counter reaches 0.
-	refc sync.Map
+	// cacheMu protects cache. It is held while a database is opened or closed, so that a provider is never handed a
+	// database that is being closed. As the level.dat of a world is only read when its database is opened, and only
+	// written by the last provider closed, it is never read while it is being written.
+	cacheMu sync.Mutex
+	// cache holds the databases currently open, keyed by the absolute path that their world was opened with. It is
+	// used to share a single *leveldb.DB between the providers of the different dimensions of a world.
+	cache = map[string]*cachedDB{}
 )
 
-func cacheLoad(k string) (*leveldb.DB, bool) {
-	if v, ok := refc.Load(k); ok {
-		refc.Store(k, v.(int)+1)
-		db, _ := cache.Load(k)
-		return db.(*leveldb.DB), true
+// cachedDB is a *leveldb.DB held in the cache together with the directory its world is stored in, the level.dat of
+// the world and the amount of providers using it.
+type cachedDB struct {
+	db *leveldb.DB
+	// dir is the directory that the world is stored in. It differs from the path the world was opened with for worlds
+	// extracted from a .mcworld archive.
+	dir string
+	// readOnly is true if the database was opened for reading only, in which case it may only be shared with other
+	// read-only providers.
+	readOnly bool
+	refs     int
+
+	// mu protects d and changed.
+	mu sync.Mutex
+	// d holds the level.dat of the world. It is shared by all providers of the world, so that settings saved by any
+	// of them are written when the last of them is closed.
+	d data
+	// changed is true if the level.dat of the world was changed, which is the case if the world had none or if
+	// settings were saved to it by any of its providers. The level.dat is only written if it was changed.
+	changed bool
+}
+
+// cacheKey returns the key of the world path passed in the cache, so that different paths to the same world share a
+// database.
+func cacheKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
 	}
-	return nil, false
+	return filepath.Clean(path)
 }
 
-func cacheStore(k string, db *leveldb.DB) {
-	cache.Store(k, db)
-	if v, ok := refc.LoadOrStore(k, 1); ok {
-		refc.Store(k, v.(int)+1)
+// cacheOpen returns the cachedDB of the world at the path passed, calling open to open the database and read the
+// level.dat of the world if no provider has the world open yet. readOnly specifies if the database is opened for
+// reading only. A world cannot be open for reading only and for writing at the same time. Every call that does not
+// return an error must be followed by a call to cacheRelease.
+func cacheOpen(path string, readOnly bool, open func() (*cachedDB, error)) (*cachedDB, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	k := cacheKey(path)
+	if c, ok := cache[k]; ok {
+		if c.readOnly && !readOnly {
+			return nil, fmt.Errorf("world %v is already open for reading only", path)
+		} else if !c.readOnly && readOnly {
+			return nil, fmt.Errorf("world %v is already open for writing", path)
+		}
+		c.refs++
+		return c, nil
 	}
+	c, err := open()
+	if err != nil {
+		return nil, err
+	}
+	c.readOnly, c.refs = readOnly, 1
+	cache[k] = c
+	return c, nil
 }
 
-func cacheDelete(k string) int {
-	v, _ := refc.Load(k)
-	if v == 1 {
-		refc.Delete(k)
-		return 0
+// cacheRelease releases a reference to the database of the world at the path passed. If it was the last reference,
+// last is called to write the remaining files of the world and close the database, while no other provider can open
+// the world.
+func cacheRelease(path string, last func() error) error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	k := cacheKey(path)
+	c, ok := cache[k]
+	if !ok {
+		return fmt.Errorf("database of %v is not open", path)
+	}
+	if c.refs--; c.refs > 0 {
+		return nil
 	}
-	refc.Store(k, v.(int)-1)
-	return v.(int) - 1
+	delete(cache, k)
+	return last()
 }
@@ -0,0 +1,307 @@
+package mcdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem is a broken entry in the leveldb database of a world, as found by Provider.Check.
+type Problem struct {
+	// Key is the key of the broken entry.
+	Key []byte
+	// Description describes what is wrong with the entry.
+	Description string
+	// fix is the data that the entry is replaced with when the Problem is repaired. If nil, the entry is deleted.
+	fix []byte
+}
+
+// String returns the key of the entry with the Problem in a readable form, followed by its description.
+func (p Problem) String() string {
+	return keyString(p.Key) + ": " + p.Description
+}
+
+// Repair returns a description of what Provider.Repair does to repair the Problem.
+func (p Problem) Repair() string {
+	if p.fix == nil {
+		return "delete the entry"
+	}
+	return "rewrite the entry"
+}
+
+// CheckLevelDat checks if the level.dat file in the world directory passed is present and can be decoded. An error
+// describing the problem is returned if it cannot. A world with a broken level.dat cannot be opened with New, but it
+// can be once the level.dat is removed, in which case a default one is created.
+func CheckLevelDat(dir string) error {
+	f, err := ioutil.ReadFile(filepath.Join(dir, "level.dat"))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("level.dat is missing")
+	} else if err != nil {
+		return fmt.Errorf("error reading level.dat: %w", err)
+	}
+	if len(f) < 8 {
+		return fmt.Errorf("level.dat of %v bytes has no header", len(f))
+	}
+	if length := binary.LittleEndian.Uint32(f[4:]); int(length) != len(f)-8 {
+		return fmt.Errorf("level.dat header holds a length of %v bytes, but has %v bytes of data", length, len(f)-8)
+	}
+	var d data
+	if err := nbt.UnmarshalEncoding(f[8:], &d, nbt.LittleEndian); err != nil {
+		return fmt.Errorf("error decoding level.dat NBT: %w", err)
+	}
+	return nil
+}
+
+// Check walks every entry in the leveldb database of the world and returns the problems found with them. Entries of
+// all dimensions are checked, not only those of the dimension of the Provider. Sub chunks are decoded to validate
+// their version and palettes, and biomes, block entities, entities and players are decoded too. Chunks that have data
+// but no version, which Minecraft does not load, are reported as well.
+func (p *Provider) Check() ([]Problem, error) {
+	iter := p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	var problems []Problem
+	// versions maps the index of every chunk with data to whether that chunk has a version entry.
+	versions := make(map[string]bool)
+	for iter.Next() {
+		// The key and value are copied, as the iterator reuses them and fixes may refer to the value.
+		key, value := append([]byte(nil), iter.Key()...), append([]byte(nil), iter.Value()...)
+		if k, ok := parseChunkKey(key); ok {
+			versions[string(k.index)] = versions[string(k.index)] || k.tag == keyVersion || k.tag == keyVersionOld
+			if description, fix, broken := checkChunkEntry(k, value); broken {
+				problems = append(problems, Problem{Key: key, Description: description, fix: fix})
+			}
+			continue
+		}
+		s := string(key)
+		if strings.HasPrefix(s, keyActorPrefix) || strings.HasPrefix(s, "player_") || s == keyLocalPlayer {
+			if description, fix, broken := checkNBT(value, false); broken {
+				problems = append(problems, Problem{Key: key, Description: description, fix: fix})
+			}
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("error iterating database: %w", err)
+	}
+	for index, ok := range versions {
+		if !ok {
+			problems = append(problems, Problem{
+				Key:         append([]byte(index), keyVersion),
+				Description: "chunk has data but no version, so it is not loaded",
+				fix:         []byte{chunkVersion},
+			})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		return bytes.Compare(problems[i].Key, problems[j].Key) < 0
+	})
+	return problems, nil
+}
+
+// Repair repairs the problems passed, as returned by Check, by deleting or rewriting their entries.
+func (p *Provider) Repair(problems []Problem) error {
+	batch := new(leveldb.Batch)
+	for _, problem := range problems {
+		if problem.fix == nil {
+			batch.Delete(problem.Key)
+			continue
+		}
+		batch.Put(problem.Key, problem.fix)
+	}
+	if err := p.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("error repairing database: %w", err)
+	}
+	return nil
+}
+
+// chunkKey is a parsed key of an entry holding chunk data.
+type chunkKey struct {
+	// index is the chunk index that the key starts with, which holds the position and dimension of the chunk.
+	index []byte
+	pos   world.ChunkPos
+	dim   world.Dimension
+	tag   byte
+	// y is the Y of the sub chunk for keys with the keySubChunkData tag.
+	y int8
+}
+
+// parseChunkKey parses the key passed as the key of an entry holding chunk data. False is returned if the key does
+// not hold chunk data.
+func parseChunkKey(key []byte) (chunkKey, bool) {
+	var k chunkKey
+	switch len(key) {
+	case 9, 10:
+		k.index, k.dim = key[:8], world.Overworld
+	case 13, 14:
+		dim, ok := world.DimensionByID(int(binary.LittleEndian.Uint32(key[8:])))
+		if !ok || dim == world.Overworld {
+			return k, false
+		}
+		k.index, k.dim = key[:12], dim
+	default:
+		return k, false
+	}
+	k.tag = key[len(k.index)]
+	if (len(key)-len(k.index) == 2) != (k.tag == keySubChunkData) {
+		return k, false
+	}
+	switch k.tag {
+	case keySubChunkData:
+		k.y = int8(key[len(key)-1])
+	case keyVersion, keyVersionOld, keyBlockEntities, keyEntities, keyFinalisation, key3DData, key2DData, keyChecksums:
+	default:
+		return k, false
+	}
+	k.pos = world.ChunkPos{int32(binary.LittleEndian.Uint32(key)), int32(binary.LittleEndian.Uint32(key[4:]))}
+	return k, true
+}
+
+// keyString formats the key passed in a readable form, such as "chunk 3, -4 in Nether: sub chunk 5".
+func keyString(key []byte) string {
+	k, ok := parseChunkKey(key)
+	if !ok {
+		return fmt.Sprintf("%q", key)
+	}
+	var entry string
+	switch k.tag {
+	case keySubChunkData:
+		entry = fmt.Sprintf("sub chunk %v", k.y)
+	case keyVersion, keyVersionOld:
+		entry = "version"
+	case keyBlockEntities:
+		entry = "block entities"
+	case keyEntities:
+		entry = "entities"
+	case keyFinalisation:
+		entry = "finalisation"
+	case key3DData:
+		entry = "3D data"
+	case key2DData:
+		entry = "2D data"
+	case keyChecksums:
+		entry = "checksums"
+	}
+	return fmt.Sprintf("chunk %v, %v in %v: %v", k.pos.X(), k.pos.Z(), k.dim, entry)
+}
+
+// checkChunkEntry checks the value of the chunk entry with the key passed. If the entry is broken, a description of
+// the problem is returned, together with the data that the entry should be replaced with, or nil if it should be
+// deleted.
+func checkChunkEntry(k chunkKey, value []byte) (description string, fix []byte, broken bool) {
+	r := k.dim.Range()
+	switch k.tag {
+	case keySubChunkData:
+		return checkSubChunk(k.y, value, r)
+	case keyVersion, keyVersionOld:
+		if len(value) != 1 {
+			return fmt.Sprintf("version of %v bytes is not a single byte", len(value)), []byte{chunkVersion}, true
+		}
+	case keyFinalisation:
+		if len(value) != 4 {
+			// The chunk is marked as fully finalised, which is what vanilla worlds generally hold.
+			return fmt.Sprintf("finalisation of %v bytes is not an int32", len(value)), []byte{2, 0, 0, 0}, true
+		}
+	case key3DData:
+		return check3DData(value, r)
+	case key2DData:
+		if len(value) != 768 {
+			return fmt.Sprintf("2D data of %v bytes is not 768 bytes", len(value)), nil, true
+		}
+	case keyBlockEntities:
+		return checkNBT(value, true)
+	case keyEntities:
+		return checkNBT(value, false)
+	}
+	return "", nil, false
+}
+
+// checkSubChunk checks if the sub chunk data passed, stored at the Y passed, can be decoded and only holds palette
+// indices that point to a value in its palettes. A sub chunk that holds a different Y than the one it is stored at is
+// rewritten with the correct Y.
+func checkSubChunk(y int8, value []byte, r cube.Range) (description string, fix []byte, broken bool) {
+	if int(y) < r.Min()>>4 || int(y) > r.Max()>>4 {
+		return fmt.Sprintf("sub chunk is outside the dimension height of %v", r), nil, true
+	}
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	c := chunk.New(air, r)
+	expected := uint8(int(y) - r.Min()>>4)
+	index := expected
+	sub, err := chunk.DecodeSubChunk(bytes.NewBuffer(value), c, &index, chunk.DiskEncoding)
+	if err != nil {
+		return fmt.Sprintf("error decoding sub chunk: %v", err), nil, true
+	}
+	for i, storage := range sub.Layers() {
+		values := storage.Palette().Len()
+		for x := byte(0); x < 16; x++ {
+			for yy := byte(0); yy < 16; yy++ {
+				for z := byte(0); z < 16; z++ {
+					if paletteIndex := storage.PaletteIndex(x, yy, z); int(paletteIndex) >= values {
+						return fmt.Sprintf("layer %v has palette index %v, but its palette has %v values", i, paletteIndex, values), nil, true
+					}
+				}
+			}
+		}
+	}
+	if index != expected {
+		// Only version 9 sub chunks hold their Y, at the third byte.
+		fix = append([]byte(nil), value...)
+		fix[2] = byte(y)
+		return fmt.Sprintf("sub chunk holds Y %v", int(index)+r.Min()>>4), fix, true
+	}
+	return "", nil, false
+}
+
+// check3DData checks if the 3D data passed holds a height map and biomes that can be decoded. Broken biomes are
+// replaced with biomes of the default biome, keeping the height map if present.
+func check3DData(value []byte, r cube.Range) (description string, fix []byte, broken bool) {
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	fix = make([]byte, 512, 512+1024)
+	fix = append(fix, chunk.Encode(chunk.New(air, r), chunk.DiskEncoding).Biomes...)
+	if len(value) < 512 {
+		return fmt.Sprintf("3D data of %v bytes has no height map", len(value)), fix, true
+	}
+	if _, err := chunk.DiskDecode(chunk.SerialisedData{Biomes: value[512:]}, r); err != nil {
+		copy(fix, value[:512])
+		return fmt.Sprintf("error decoding biomes: %v", err), fix, true
+	}
+	return "", nil, false
+}
+
+// checkNBT checks if the data passed holds only NBT compound tags appended to each other, as block entities,
+// entities and players are stored. If requireID is true, every compound must have an id. If any of the compounds are
+// broken, the entry is rewritten with only the compounds before it, or deleted if there are none.
+func checkNBT(value []byte, requireID bool) (description string, fix []byte, broken bool) {
+	buf := bytes.NewBuffer(value)
+	dec := nbt.NewDecoderWithEncoding(buf, nbt.LittleEndian)
+	for buf.Len() != 0 {
+		offset := len(value) - buf.Len()
+		var m map[string]interface{}
+		err := dec.Decode(&m)
+		if err == nil && requireID {
+			if id, ok := m["id"].(string); !ok || id == "" {
+				err = fmt.Errorf("compound has no id")
+			}
+		}
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			if offset != 0 {
+				fix = value[:offset]
+			}
+			return fmt.Sprintf("error decoding NBT at offset %v: %v", offset, err), fix, true
+		}
+	}
+	return "", nil, false
+}
@@ -0,0 +1,106 @@
+package mcdb
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"testing"
+)
+
+// TestCheckSubChunk tests that Check finds a deliberately corrupted sub chunk, and that Repair repairs it so that it
+// is no longer found.
+func TestCheckSubChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(value []byte) []byte
+		repair  string
+	}{
+		{name: "truncated", corrupt: func(value []byte) []byte {
+			return value[:3]
+		}, repair: "delete the entry"},
+		{name: "unknown version", corrupt: func(value []byte) []byte {
+			value[0] = 0xff
+			return value
+		}, repair: "delete the entry"},
+		{name: "broken palette", corrupt: func(value []byte) []byte {
+			// The palette is at the end of the sub chunk, so this cuts its last entry short.
+			return value[:len(value)-4]
+		}, repair: "delete the entry"},
+		{name: "wrong Y", corrupt: func(value []byte) []byte {
+			value[2] = 5
+			return value
+		}, repair: "rewrite the entry"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := newTestProvider(t, t.TempDir(), world.Overworld)
+			pos := world.ChunkPos{3, -2}
+			saveTestChunk(t, p, pos)
+
+			problems, err := p.Check()
+			if err != nil {
+				t.Fatalf("error checking world: %v", err)
+			}
+			if len(problems) != 0 {
+				t.Fatalf("expected no problems in saved chunk, got %v", problems)
+			}
+
+			key := append(p.index(pos), keySubChunkData, 0)
+			value, err := p.db.Get(key, nil)
+			if err != nil {
+				t.Fatalf("error reading sub chunk: %v", err)
+			}
+			if err := p.db.Put(key, test.corrupt(value), nil); err != nil {
+				t.Fatalf("error writing corrupted sub chunk: %v", err)
+			}
+
+			if problems, err = p.Check(); err != nil {
+				t.Fatalf("error checking world: %v", err)
+			}
+			if len(problems) != 1 || string(problems[0].Key) != string(key) {
+				t.Fatalf("expected a problem with the corrupted sub chunk, got %v", problems)
+
+			}
+			if repair := problems[0].Repair(); repair != test.repair {
+				t.Errorf("expected repair %q, got %q", test.repair, repair)
+			}
+			if err := p.Repair(problems); err != nil {
+				t.Fatalf("error repairing world: %v", err)
+			}
+			if problems, err = p.Check(); err != nil {
+				t.Fatalf("error checking world: %v", err)
+			}
+			if len(problems) != 0 {
+				t.Errorf("expected no problems after repairing, got %v", problems)
+			}
+		})
+	}
+}
+
+// newTestProvider opens a Provider for the dimension passed of the world in the directory passed. The Provider is
+// closed when the test finishes.
+func newTestProvider(t *testing.T, dir string, dim world.Dimension) *Provider {
+	p, err := New(dir, dim)
+	if err != nil {
+		t.Fatalf("error opening world: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := p.Close(); err != nil {
+			t.Errorf("error closing world: %v", err)
+		}
+	})
+	return p
+}
+
+// saveTestChunk saves a chunk with a stone block at Y 0 to the Provider passed.
+func saveTestChunk(t *testing.T, p *Provider, pos world.ChunkPos) {
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	stone, ok := chunk.StateToRuntimeID("minecraft:stone", map[string]interface{}{"stone_type": "stone"})
+	if !ok {
+		t.Fatalf("stone block state does not exist")
+	}
+	c := chunk.New(air, p.dim.Range())
+	c.SetBlock(0, 0, 0, 0, stone)
+	if err := p.SaveChunk(pos, c); err != nil {
+		t.Fatalf("error saving chunk: %v", err)
+	}
+}
@@ -43,3 +43,12 @@ const (
 	keyScoreboard         = "scoreboard"
 	keyLocalPlayer        = "~local_player"
 )
+
+// Keys of actors, which newer worlds store separately from the chunks they are in.
+const (
+	// keyActorDigest is followed by the index of a chunk and holds the unique IDs of the actors in the chunk, as int64s
+	// appended to each other.
+	keyActorDigest = "digp"
+	// keyActorPrefix is followed by the unique ID of an actor and holds the NBT of the actor.
+	keyActorPrefix = "actorprefix"
+)
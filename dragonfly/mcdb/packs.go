@@ -0,0 +1,142 @@
+package mcdb
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/sandertv/gophertunnel/minecraft/resource"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// worldPack is an entry of the world_resource_packs.json and world_behavior_packs.json files of a world, which list
+// the packs applied to the world.
+type worldPack struct {
+	PackID  string `json:"pack_id"`
+	Version [3]int `json:"version"`
+}
+
+// SavePack saves the resource.Pack passed to the world, so that it is applied when the world is opened. Packs with
+// behaviours are saved to the behavior_packs folder, and all other packs to the resource_packs folder. The pack is
+// added to world_behavior_packs.json or world_resource_packs.json, replacing any version of the pack that was saved
+// previously. Encrypted packs cannot be saved and result in an error.
+func (p *Provider) SavePack(pack *resource.Pack) error {
+	if pack.Encrypted() {
+		return fmt.Errorf("pack %v is encrypted", pack.Name())
+	}
+	folder, list := "resource_packs", "world_resource_packs.json"
+	if pack.HasBehaviours() {
+		folder, list = "behavior_packs", "world_behavior_packs.json"
+	}
+
+	data := make([]byte, pack.Len())
+	if _, err := pack.ReadAt(data, 0); err != nil && err != io.EOF {
+		return fmt.Errorf("error reading pack %v: %w", pack.Name(), err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("error reading pack %v archive: %w", pack.Name(), err)
+	}
+	root, err := manifestFolder(r)
+	if err != nil {
+		return fmt.Errorf("error reading pack %v: %w", pack.Name(), err)
+	}
+
+	dir := filepath.Join(p.dir, folder, pack.UUID())
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error removing old pack %v: %w", pack.Name(), err)
+	}
+	for _, f := range r.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, "\\", "/"))
+		if root != "." {
+			if !strings.HasPrefix(name, root+"/") {
+				// Files outside the folder holding the manifest.json are not part of the pack.
+				continue
+			}
+			name = strings.TrimPrefix(name, root+"/")
+		}
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+			continue
+		}
+		if err := extractPackFile(f, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return fmt.Errorf("error saving pack %v: %w", pack.Name(), err)
+		}
+	}
+
+	manifest := pack.Manifest()
+	return p.addWorldPack(list, worldPack{PackID: pack.UUID(), Version: manifest.Header.Version})
+}
+
+// addWorldPack adds a pack to the list of packs in the file with the name passed, replacing the entry of the pack if it
+// was already present.
+func (p *Provider) addWorldPack(list string, pack worldPack) error {
+	file := filepath.Join(p.dir, list)
+
+	var packs []worldPack
+	if data, err := os.ReadFile(file); err == nil {
+		if err := json.Unmarshal(data, &packs); err != nil {
+			return fmt.Errorf("error decoding %v: %w", list, err)
+		}
+	}
+	replaced := false
+	for i, existing := range packs {
+		if existing.PackID == pack.PackID {
+			packs[i], replaced = pack, true
+		}
+	}
+	if !replaced {
+		packs = append(packs, pack)
+	}
+
+	data, err := json.MarshalIndent(packs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %v: %w", list, err)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return fmt.Errorf("error writing %v: %w", list, err)
+	}
+	return nil
+}
+
+// manifestFolder returns the folder in the pack archive passed that holds the manifest.json of the pack. This is
+// either the root of the archive, in which case "." is returned, or one of the folders directly in it.
+func manifestFolder(r *zip.Reader) (string, error) {
+	if _, err := fs.Stat(r, "manifest.json"); err == nil {
+		return ".", nil
+	}
+	entries, err := fs.ReadDir(r, ".")
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(r, path.Join(entry.Name(), "manifest.json")); err == nil {
+			return entry.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("pack has no manifest.json")
+}
+
+// extractPackFile writes the file from a pack archive to the path passed.
+func extractPackFile(f *zip.File, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
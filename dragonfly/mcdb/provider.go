@@ -23,7 +23,14 @@ type Provider struct {
 	db  *leveldb.DB
 	dim world.Dimension
 	dir string
-	d   data
+	// c is the cachedDB of the world, which holds the level.dat shared by all providers of the world.
+	c *cachedDB
+	// archive is the path of the .mcworld archive that the world was extracted from, if it was opened from one.
+	archive string
+	// readOnly is true if the Provider was opened using Open, in which case the world is never written to.
+	readOnly bool
+	// closed is true once Close has been called.
+	closed bool
 }
 
 // chunkVersion is the current version of chunks.
@@ -32,108 +39,182 @@ const chunkVersion = 27
 // New creates a new provider reading and writing from/to files under the path passed. If a world is present
 // at the path, New will parse its data and initialise the world with it. If the data cannot be parsed, an
 // error is returned.
+//
+// If the path is a .mcworld archive, it is extracted to a temporary directory that the world is read from and that is
+// removed again when the last provider of the world is closed. Changes made to the world are not written back to the
+// archive, which may be done using WriteArchive instead.
 func New(dir string, d world.Dimension) (*Provider, error) {
-	_ = os.MkdirAll(filepath.Join(dir, "db"), 0777)
+	return open(&Provider{dir: dir, dim: d})
+}
 
-	p := &Provider{dir: dir, dim: d}
-	if _, err := os.Stat(filepath.Join(dir, "level.dat")); os.IsNotExist(err) {
-		// A level.dat was not currently present for the world.
-		p.initDefaultLevelDat()
-	} else {
-		f, err := ioutil.ReadFile(filepath.Join(dir, "level.dat"))
-		if err != nil {
-			return nil, fmt.Errorf("error opening level.dat file: %w", err)
-		}
-		// The first 8 bytes are a useless header (version and length): We don't need it.
-		if len(f) < 8 {
-			// The file did not have enough content, meaning it is corrupted. We return an error.
-			return nil, fmt.Errorf("level.dat exists but has no data")
+// Open opens the world at the path passed for reading only. Unlike New, Open returns an error if the world has no
+// database or level.dat, and the world, including its level.dat, is never written, not even when the Provider is
+// closed. Chunks saved to a Provider returned by Open are not written. Like New, Open extracts .mcworld archives to a
+// temporary directory.
+func Open(dir string, d world.Dimension) (*Provider, error) {
+	return open(&Provider{dir: dir, dim: d, readOnly: true})
+}
+
+// open opens the database and reads the level.dat of the world of the Provider passed, unless another provider of
+// the world already did.
+func open(p *Provider) (*Provider, error) {
+	if IsArchive(p.dir) {
+		p.archive = p.dir
+	}
+	c, err := cacheOpen(p.cachePath(), p.readOnly, p.openDB)
+	if err != nil {
+		return nil, err
+	}
+	p.c, p.db, p.dir = c, c.db, c.dir
+	return p, nil
+}
+
+// cachePath returns the path that the database of the Provider is cached under, which is the path of the archive for
+// worlds opened from a .mcworld archive.
+func (p *Provider) cachePath() string {
+	if p.archive != "" {
+		return p.archive
+	}
+	return p.dir
+}
+
+// openDB opens the leveldb database and reads the level.dat of the world of the Provider, extracting the world first
+// if it is in an archive.
+func (p *Provider) openDB() (*cachedDB, error) {
+	dir := p.dir
+	if p.archive != "" {
+		var err error
+		if dir, err = extractTemp(p.archive); err != nil {
+			return nil, err
 		}
-		if err := nbt.UnmarshalEncoding(f[8:], &p.d, nbt.LittleEndian); err != nil {
-			return nil, fmt.Errorf("error decoding level.dat NBT: %w", err)
+	}
+	if !p.readOnly {
+		_ = os.MkdirAll(filepath.Join(dir, "db"), 0777)
+	}
+	db, err := leveldb.OpenFile(filepath.Join(dir, "db"), &opt.Options{
+		Compression:    opt.FlateCompression,
+		BlockSize:      16 * opt.KiB,
+		ReadOnly:       p.readOnly,
+		ErrorIfMissing: p.readOnly,
+	})
+	if err != nil {
+		if p.archive != "" {
+			_ = os.RemoveAll(dir)
 		}
-		p.d.WorldStartCount++
+		return nil, fmt.Errorf("error opening leveldb database: %w", err)
 	}
-	db, ok := cacheLoad(dir)
-	if !ok {
-		var err error
-		if db, err = leveldb.OpenFile(filepath.Join(dir, "db"), &opt.Options{
-			Compression: opt.FlateCompression,
-			BlockSize:   16 * opt.KiB,
-		}); err != nil {
-			return nil, fmt.Errorf("error opening leveldb database: %w", err)
+	c := &cachedDB{db: db, dir: dir}
+	if err := c.readLevelDat(p.readOnly); err != nil {
+		_ = db.Close()
+		if p.archive != "" {
+			_ = os.RemoveAll(dir)
 		}
-		cacheStore(dir, db)
+		return nil, err
 	}
+	return c, nil
+}
 
-	p.db = db
-	return p, nil
+// readLevelDat reads the level.dat of the world, or initialises a default one if the world has none and readOnly is
+// false.
+func (c *cachedDB) readLevelDat(readOnly bool) error {
+	if _, err := os.Stat(filepath.Join(c.dir, "level.dat")); os.IsNotExist(err) {
+		if readOnly {
+			return fmt.Errorf("level.dat is missing")
+		}
+		// A level.dat was not currently present for the world.
+		c.initDefaultLevelDat()
+		c.changed = true
+		return nil
+	}
+	f, err := ioutil.ReadFile(filepath.Join(c.dir, "level.dat"))
+	if err != nil {
+		return fmt.Errorf("error opening level.dat file: %w", err)
+	}
+	// The first 8 bytes are a useless header (version and length): We don't need it.
+	if len(f) < 8 {
+		// The file did not have enough content, meaning it is corrupted. We return an error.
+		return fmt.Errorf("level.dat exists but has no data")
+	}
+	if err := nbt.UnmarshalEncoding(f[8:], &c.d, nbt.LittleEndian); err != nil {
+		return fmt.Errorf("error decoding level.dat NBT: %w", err)
+	}
+	c.d.WorldStartCount++
+	return nil
 }
 
 // initDefaultLevelDat initialises a default level.dat file.
-func (p *Provider) initDefaultLevelDat() {
-	p.d.DoDayLightCycle = true
-	p.d.DoWeatherCycle = true
-	p.d.BaseGameVersion = protocol.CurrentVersion
-	p.d.NetworkVersion = protocol.CurrentProtocol
-	p.d.LastOpenedWithVersion = minimumCompatibleClientVersion
-	p.d.MinimumCompatibleClientVersion = minimumCompatibleClientVersion
-	p.d.LevelName = "World"
-	p.d.GameType = 1
-	p.d.StorageVersion = 8
-	p.d.Generator = 2
-	p.d.Abilities.WalkSpeed = 0.1
-	p.d.PVP = true
-	p.d.WorldStartCount = 1
-	p.d.RandomTickSpeed = 1
-	p.d.FallDamage = true
-	p.d.FireDamage = true
-	p.d.DrowningDamage = true
-	p.d.CommandsEnabled = true
-	p.d.MultiPlayerGame = true
-	p.d.SpawnY = math.MaxInt32
-	p.d.Difficulty = 2
-	p.d.DoWeatherCycle = true
-	p.d.RainLevel = 1.0
-	p.d.LightningLevel = 1.0
-	p.d.ServerChunkTickRange = 6
-	p.d.NetherScale = 8
+func (c *cachedDB) initDefaultLevelDat() {
+	c.d.DoDayLightCycle = true
+	c.d.DoWeatherCycle = true
+	c.d.BaseGameVersion = protocol.CurrentVersion
+	c.d.NetworkVersion = protocol.CurrentProtocol
+	c.d.LastOpenedWithVersion = minimumCompatibleClientVersion
+	c.d.MinimumCompatibleClientVersion = minimumCompatibleClientVersion
+	c.d.LevelName = "World"
+	c.d.GameType = 1
+	c.d.StorageVersion = 8
+	c.d.Generator = 2
+	c.d.Abilities.WalkSpeed = 0.1
+	c.d.PVP = true
+	c.d.WorldStartCount = 1
+	c.d.RandomTickSpeed = 1
+	c.d.FallDamage = true
+	c.d.FireDamage = true
+	c.d.DrowningDamage = true
+	c.d.CommandsEnabled = true
+	c.d.MultiPlayerGame = true
+	c.d.SpawnY = math.MaxInt32
+	c.d.Difficulty = 2
+	c.d.DoWeatherCycle = true
+	c.d.RainLevel = 1.0
+	c.d.LightningLevel = 1.0
+	c.d.ServerChunkTickRange = 6
+	c.d.NetherScale = 8
 }
 
 // Settings returns the world.Settings of the world loaded by the Provider.
 func (p *Provider) Settings(s *world.Settings) {
-	s.Name = p.d.LevelName
-	s.Spawn = cube.Pos{int(p.d.SpawnX), int(p.d.SpawnY), int(p.d.SpawnZ)}
-	s.Time = p.d.Time
-	s.TimeCycle = p.d.DoDayLightCycle
-	s.WeatherCycle = p.d.DoWeatherCycle
-	s.RainTime = int64(p.d.RainTime)
-	s.Raining = p.d.RainLevel > 0
-	s.ThunderTime = int64(p.d.LightningTime)
-	s.Thundering = p.d.LightningLevel > 0
-	s.CurrentTick = p.d.CurrentTick
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+	s.Name = p.c.d.LevelName
+	s.Spawn = cube.Pos{int(p.c.d.SpawnX), int(p.c.d.SpawnY), int(p.c.d.SpawnZ)}
+	s.Time = p.c.d.Time
+	s.TimeCycle = p.c.d.DoDayLightCycle
+	s.WeatherCycle = p.c.d.DoWeatherCycle
+	s.RainTime = int64(p.c.d.RainTime)
+	s.Raining = p.c.d.RainLevel > 0
+	s.ThunderTime = int64(p.c.d.LightningTime)
+	s.Thundering = p.c.d.LightningLevel > 0
+	s.CurrentTick = p.c.d.CurrentTick
 	s.DefaultGameMode = p.loadDefaultGameMode()
 	s.Difficulty = p.loadDifficulty()
-	s.TickRange = p.d.ServerChunkTickRange
+	s.TickRange = p.c.d.ServerChunkTickRange
+	s.NetherScale = p.c.d.NetherScale
 }
 
 // SaveSettings saves the world.Settings passed to the level.dat.
 func (p *Provider) SaveSettings(s *world.Settings) {
-	p.d.LevelName = s.Name
-	p.d.SpawnX, p.d.SpawnY, p.d.SpawnZ = int32(s.Spawn.X()), int32(s.Spawn.Y()), int32(s.Spawn.Z())
-	p.d.Time = s.Time
-	p.d.DoDayLightCycle = s.TimeCycle
-	p.d.DoWeatherCycle = s.WeatherCycle
-	p.d.RainTime, p.d.RainLevel = int32(s.RainTime), 0
-	p.d.LightningTime, p.d.LightningLevel = int32(s.ThunderTime), 0
+	p.c.mu.Lock()
+	defer p.c.mu.Unlock()
+	p.c.changed = true
+	p.c.d.LevelName = s.Name
+	p.c.d.SpawnX, p.c.d.SpawnY, p.c.d.SpawnZ = int32(s.Spawn.X()), int32(s.Spawn.Y()), int32(s.Spawn.Z())
+	p.c.d.Time = s.Time
+	p.c.d.DoDayLightCycle = s.TimeCycle
+	p.c.d.DoWeatherCycle = s.WeatherCycle
+	p.c.d.RainTime, p.c.d.RainLevel = int32(s.RainTime), 0
+	p.c.d.LightningTime, p.c.d.LightningLevel = int32(s.ThunderTime), 0
 	if s.Raining {
-		p.d.RainLevel = 1
+		p.c.d.RainLevel = 1
 	}
 	if s.Thundering {
-		p.d.LightningLevel = 1
+		p.c.d.LightningLevel = 1
+	}
+	p.c.d.CurrentTick = s.CurrentTick
+	p.c.d.ServerChunkTickRange = s.TickRange
+	if s.NetherScale != 0 {
+		p.c.d.NetherScale = s.NetherScale
 	}
-	p.d.CurrentTick = s.CurrentTick
-	p.d.ServerChunkTickRange = s.TickRange
 	p.saveDefaultGameMode(s.DefaultGameMode)
 	p.saveDifficulty(s.Difficulty)
 }
@@ -170,19 +251,63 @@ func (p *Provider) LoadChunk(position world.ChunkPos) (c *chunk.Chunk, exists bo
 	if err != nil && err != leveldb.ErrNotFound {
 		return nil, true, fmt.Errorf("error reading block entities: %w", err)
 	}
-	data.SubChunks = make([][]byte, (p.dim.Range().Height()>>4)+1)
-	for i := range data.SubChunks {
-		data.SubChunks[i], err = p.db.Get(append(key, keySubChunkData, uint8(i+(p.dim.Range()[0]>>4))), nil)
+	if data.SubChunks, err = p.LoadSubChunkData(position); err != nil {
+		return nil, true, err
+	}
+	c, err = chunk.DiskDecode(data, p.dim.Range())
+	return c, true, err
+}
+
+// LoadSubChunkData loads the encoded data of the sub chunks of the chunk at the position passed, as it is stored in
+// the leveldb database. The sub chunks are ordered from the bottom of the dimension up, and the data of sub chunks
+// that are not present is nil.
+func (p *Provider) LoadSubChunkData(position world.ChunkPos) ([][]byte, error) {
+	key := p.index(position)
+	subs := make([][]byte, (p.dim.Range().Height()>>4)+1)
+	for i := range subs {
+		data, err := p.db.Get(append(key, keySubChunkData, uint8(i+(p.dim.Range()[0]>>4))), nil)
 		if err == leveldb.ErrNotFound {
 			// No sub chunk present at this Y level. We skip this one and move to the next, which might still
 			// be present.
 			continue
 		} else if err != nil {
-			return nil, true, fmt.Errorf("error reading sub chunk data %v: %w", i, err)
+			return nil, fmt.Errorf("error reading sub chunk data %v: %w", i, err)
 		}
+		subs[i] = data
 	}
-	c, err = chunk.DiskDecode(data, p.dim.Range())
-	return c, true, err
+	return subs, nil
+}
+
+// Positions returns the positions of all chunks stored in the dimension of the Provider. The positions are found by
+// iterating over all keys in the database that hold the version of a chunk.
+func (p *Provider) Positions() ([]world.ChunkPos, error) {
+	iter := p.db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	dim := uint32(p.dim.EncodeDimension())
+	found := make(map[world.ChunkPos]struct{})
+	var positions []world.ChunkPos
+	for iter.Next() {
+		key := iter.Key()
+		switch {
+		case len(key) == 9 && dim == 0:
+		case len(key) == 13 && dim != 0 && binary.LittleEndian.Uint32(key[8:]) == dim:
+		default:
+			continue
+		}
+		if tag := key[len(key)-1]; tag != keyVersion && tag != keyVersionOld {
+			continue
+		}
+		pos := world.ChunkPos{int32(binary.LittleEndian.Uint32(key)), int32(binary.LittleEndian.Uint32(key[4:]))}
+		if _, ok := found[pos]; !ok {
+			found[pos] = struct{}{}
+			positions = append(positions, pos)
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return nil, fmt.Errorf("error iterating database: %w", err)
+	}
+	return positions, nil
 }
 
 // SaveChunk saves a chunk at the position passed to the leveldb database. Its version is written as the
@@ -207,7 +332,7 @@ func (p *Provider) SaveChunk(position world.ChunkPos, c *chunk.Chunk) error {
 
 // loadDefaultGameMode returns the default game mode stored in the level.dat.
 func (p *Provider) loadDefaultGameMode() world.GameMode {
-	switch p.d.GameType {
+	switch p.c.d.GameType {
 	default:
 		return world.GameModeSurvival
 	case 1:
@@ -223,19 +348,19 @@ func (p *Provider) loadDefaultGameMode() world.GameMode {
 func (p *Provider) saveDefaultGameMode(mode world.GameMode) {
 	switch mode {
 	case world.GameModeSurvival:
-		p.d.GameType = 0
+		p.c.d.GameType = 0
 	case world.GameModeCreative:
-		p.d.GameType = 1
+		p.c.d.GameType = 1
 	case world.GameModeAdventure:
-		p.d.GameType = 2
+		p.c.d.GameType = 2
 	case world.GameModeSpectator:
-		p.d.GameType = 3
+		p.c.d.GameType = 3
 	}
 }
 
 // loadDifficulty loads the difficulty stored in the level.dat.
 func (p *Provider) loadDifficulty() world.Difficulty {
-	switch p.d.Difficulty {
+	switch p.c.d.Difficulty {
 	default:
 		return world.DifficultyNormal
 	case 0:
@@ -251,13 +376,13 @@ func (p *Provider) loadDifficulty() world.Difficulty {
 func (p *Provider) saveDifficulty(d world.Difficulty) {
 	switch d {
 	case world.DifficultyPeaceful:
-		p.d.Difficulty = 0
+		p.c.d.Difficulty = 0
 	case world.DifficultyEasy:
-		p.d.Difficulty = 1
+		p.c.d.Difficulty = 1
 	case world.DifficultyNormal:
-		p.d.Difficulty = 2
+		p.c.d.Difficulty = 2
 	case world.DifficultyHard:
-		p.d.Difficulty = 3
+		p.c.d.Difficulty = 3
 	}
 }
 
@@ -297,22 +422,80 @@ func (p *Provider) SaveBlockNBT(position world.ChunkPos, data []map[string]inter
 	return p.db.Put(append(p.index(position), keyBlockEntities), buf.Bytes(), nil)
 }
 
-// Close closes the provider, saving any file that might need to be saved, such as the level.dat.
+// LoadEntityNBT loads all entities from the chunk position passed. Entities are read both from the chunk itself, as
+// older worlds store them, and from the actors that newer worlds store separately and list in the actor digest of the
+// chunk.
+func (p *Provider) LoadEntityNBT(position world.ChunkPos) ([]map[string]interface{}, error) {
+	index := p.index(position)
+	data, err := p.db.Get(append(index, keyEntities), nil)
+	if err != leveldb.ErrNotFound && err != nil {
+		return nil, err
+	}
+	ids, err := p.db.Get(append([]byte(keyActorDigest), index...), nil)
+	if err != leveldb.ErrNotFound && err != nil {
+		return nil, fmt.Errorf("error reading actor digest: %w", err)
+	}
+	for i := 0; i+8 <= len(ids); i += 8 {
+		actor, err := p.db.Get(append([]byte(keyActorPrefix), ids[i:i+8]...), nil)
+		if err == leveldb.ErrNotFound {
+			// The digest may list actors that were already removed.
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("error reading actor: %w", err)
+		}
+		data = append(data, actor...)
+	}
+	var a []map[string]interface{}
+
+	buf := bytes.NewBuffer(data)
+	dec := nbt.NewDecoderWithEncoding(buf, nbt.LittleEndian)
+
+	for buf.Len() != 0 {
+		var m map[string]interface{}
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("error decoding entity NBT: %w", err)
+		}
+		a = append(a, m)
+	}
+	return a, nil
+}
+
+// Close closes the provider. The level.dat and levelname.txt are only written, and the database is only closed, once
+// the last provider of the world is closed. They are only written if the world had no level.dat or if settings were
+// saved using SaveSettings by any of the providers of the world, and never for worlds opened using Open. Closing a
+// provider more than once has no effect.
 func (p *Provider) Close() error {
-	p.d.LastPlayed = time.Now().Unix()
-	if cacheDelete(p.dir) != 0 {
-		// The same provider is still alive elsewhere. Don't store the data to the level.dat and levelname.txt just yet.
+	if p.closed {
 		return nil
 	}
-	f, err := os.OpenFile(filepath.Join(p.dir, "level.dat"), os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
+	p.closed = true
+	return cacheRelease(p.cachePath(), func() error {
+		var err error
+		if !p.readOnly && p.c.changed {
+			err = p.c.writeLevelDat()
+		}
+		if closeErr := p.closeDB(); err == nil {
+			err = closeErr
+		}
+		return err
+	})
+}
+
+// writeLevelDat writes the level.dat and levelname.txt of the world, updating the time it was last played.
+func (c *cachedDB) writeLevelDat() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.d.LastPlayed = time.Now().Unix()
+	f, err := os.OpenFile(filepath.Join(c.dir, "level.dat"), os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return fmt.Errorf("error opening level.dat file: %w", err)
 	}
 
 	buf := bytes.NewBuffer(nil)
 	_ = binary.Write(buf, binary.LittleEndian, int32(3))
-	nbtData, err := nbt.MarshalEncoding(p.d, nbt.LittleEndian)
+	nbtData, err := nbt.MarshalEncoding(c.d, nbt.LittleEndian)
 	if err != nil {
+		_ = f.Close()
 		return fmt.Errorf("error encoding level.dat to NBT: %w", err)
 	}
 	_ = binary.Write(buf, binary.LittleEndian, int32(len(nbtData)))
@@ -324,10 +507,24 @@ func (p *Provider) Close() error {
 		return fmt.Errorf("error closing level.dat: %w", err)
 	}
 	//noinspection SpellCheckingInspection
-	if err := ioutil.WriteFile(filepath.Join(p.dir, "levelname.txt"), []byte(p.d.LevelName), 0644); err != nil {
+	if err := ioutil.WriteFile(filepath.Join(c.dir, "levelname.txt"), []byte(c.d.LevelName), 0644); err != nil {
 		return fmt.Errorf("error writing levelname.txt: %w", err)
 	}
-	return p.db.Close()
+	return nil
+}
+
+// closeDB closes the leveldb database of the Provider, and removes the directory that the world was extracted to if
+// it was opened from an archive.
+func (p *Provider) closeDB() error {
+	if err := p.db.Close(); err != nil {
+		return fmt.Errorf("error closing leveldb database: %w", err)
+	}
+	if p.archive != "" {
+		if err := os.RemoveAll(p.dir); err != nil {
+			return fmt.Errorf("error removing extracted archive: %w", err)
+		}
+	}
+	return nil
 }
 
 // index returns a byte buffer holding the written index of the chunk position passed. If the dimension passed to New
@@ -0,0 +1,81 @@
+package mcdb
+
+import (
+	"fmt"
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/df-mc/goleveldb/leveldb/util"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+)
+
+// DeleteChunk deletes all data of the chunk at the position passed from the dimension of the Provider, including
+// its sub chunks, biomes, block entities and entities. Actors stored separately from the chunk in newer worlds are
+// deleted as well.
+func (p *Provider) DeleteChunk(position world.ChunkPos) error {
+	index := p.index(position)
+	batch := new(leveldb.Batch)
+
+	iter := p.db.NewIterator(util.BytesPrefix(index), nil)
+	for iter.Next() {
+		// The index of a chunk in the overworld is a prefix of the index of the chunk at the same position in other
+		// dimensions, so only keys of the length of chunk keys in this dimension are deleted.
+		if key := iter.Key(); len(key) == len(index)+1 || len(key) == len(index)+2 && key[len(index)] == keySubChunkData {
+			batch.Delete(append([]byte(nil), key...))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return fmt.Errorf("error iterating chunk %v: %w", position, err)
+	}
+
+	digest := append([]byte(keyActorDigest), index...)
+	ids, err := p.db.Get(digest, nil)
+	if err != nil && err != leveldb.ErrNotFound {
+		return fmt.Errorf("error reading actor digest of chunk %v: %w", position, err)
+	}
+	for i := 0; i+8 <= len(ids); i += 8 {
+		batch.Delete(append([]byte(keyActorPrefix), ids[i:i+8]...))
+	}
+	if err == nil {
+		batch.Delete(digest)
+	}
+	if err := p.db.Write(batch, nil); err != nil {
+		return fmt.Errorf("error deleting chunk %v: %w", position, err)
+	}
+	return nil
+}
+
+// ChunkEmpty checks if the chunk at the position passed holds no blocks other than air, meaning all of its sub chunks
+// are either absent or empty. Chunks that do not exist are also considered empty.
+func (p *Provider) ChunkEmpty(position world.ChunkPos) (bool, error) {
+	subs, err := p.LoadSubChunkData(position)
+	if err != nil {
+		return false, err
+	}
+	present := false
+	for _, sub := range subs {
+		present = present || sub != nil
+	}
+	if !present {
+		// No sub chunks are stored at all, so there is no need to decode the chunk.
+		return true, nil
+	}
+	c, ok, err := p.LoadChunk(position)
+	if err != nil || !ok {
+		return !ok, err
+	}
+	c.Compact()
+	for _, sub := range c.Sub() {
+		if !sub.Empty() {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Compact compacts the leveldb database of the world, so that the space taken up by deleted entries is freed.
+func (p *Provider) Compact() error {
+	if err := p.db.CompactRange(util.Range{}); err != nil {
+		return fmt.Errorf("error compacting database: %w", err)
+	}
+	return nil
+}
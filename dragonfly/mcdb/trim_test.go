@@ -0,0 +1,82 @@
+package mcdb
+
+import (
+	"bytes"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"testing"
+)
+
+// TestDeleteChunk tests that DeleteChunk deletes every key of a chunk, including its actors and actor digest, while
+// leaving the chunk at the same position in other dimensions and neighbouring chunks untouched.
+func TestDeleteChunk(t *testing.T) {
+	tests := []struct {
+		name       string
+		dim, other world.Dimension
+	}{
+		{name: "overworld", dim: world.Overworld, other: world.Nether},
+		{name: "nether", dim: world.Nether, other: world.Overworld},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			p, other := newTestProvider(t, dir, test.dim), newTestProvider(t, dir, test.other)
+			pos, neighbour := world.ChunkPos{-5, 7}, world.ChunkPos{-4, 7}
+
+			saveTestChunk(t, p, pos)
+			saveTestChunk(t, p, neighbour)
+			saveTestChunk(t, other, pos)
+			actors := map[*Provider]string{p: "\x01\x00\x00\x00\x00\x00\x00\x00", other: "\x02\x00\x00\x00\x00\x00\x00\x00"}
+			for prov, id := range actors {
+				for _, key := range [][]byte{append(prov.index(pos), keyBlockEntities), append(prov.index(pos), keyEntities)} {
+					if err := p.db.Put(key, []byte{}, nil); err != nil {
+						t.Fatalf("error writing chunk entry: %v", err)
+					}
+				}
+				if err := p.db.Put(append([]byte(keyActorDigest), prov.index(pos)...), []byte(id), nil); err != nil {
+					t.Fatalf("error writing actor digest: %v", err)
+				}
+				if err := p.db.Put([]byte(keyActorPrefix+id), []byte{}, nil); err != nil {
+					t.Fatalf("error writing actor: %v", err)
+				}
+			}
+
+			if err := p.DeleteChunk(pos); err != nil {
+				t.Fatalf("error deleting chunk: %v", err)
+			}
+			index := p.index(pos)
+			deleted := func(key []byte) bool {
+				if bytes.Equal(key, append([]byte(keyActorDigest), index...)) || string(key) == keyActorPrefix+actors[p] {
+					return true
+				}
+				// Keys of the chunk consist of its index followed by a tag, and a Y for sub chunks, so that the longer
+				// index of the chunk in another dimension is not matched.
+				return bytes.HasPrefix(key, index) && (len(key) == len(index)+1 || len(key) == len(index)+2 && key[len(index)] == keySubChunkData)
+			}
+			kept := [][]byte{
+				append(p.index(neighbour), keyVersion),
+				append(other.index(pos), keyVersion),
+				append(other.index(pos), keyEntities),
+				append([]byte(keyActorDigest), other.index(pos)...),
+				[]byte(keyActorPrefix + actors[other]),
+			}
+
+			iter := p.db.NewIterator(nil, nil)
+			defer iter.Release()
+			found := make([]bool, len(kept))
+			for iter.Next() {
+				key := iter.Key()
+				if deleted(key) {
+					t.Errorf("key %v was not deleted", keyString(key))
+				}
+				for i, k := range kept {
+					found[i] = found[i] || bytes.Equal(key, k)
+				}
+			}
+			for i, ok := range found {
+				if !ok {
+					t.Errorf("key %v was deleted", keyString(kept[i]))
+				}
+			}
+		})
+	}
+}
@@ -73,8 +73,8 @@ func registerBlockState(s blockState) {
 
 	nbtBlocks = append(nbtBlocks, false)
 	randomTickBlocks = append(randomTickBlocks, false)
-	chunk.FilteringBlocks = append(chunk.FilteringBlocks, 15)
-	chunk.LightBlocks = append(chunk.LightBlocks, 0)
+	chunk.FilteringBlocks = append(chunk.FilteringBlocks, lightFilter(s))
+	chunk.LightBlocks = append(chunk.LightBlocks, lightEmission(s))
 }
 
 // blockState holds a combination of a name and properties, together with a version.
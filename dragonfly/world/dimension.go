@@ -32,6 +32,20 @@ type (
 	end       struct{}
 )
 
+// DimensionByID returns the Dimension with the ID passed, as returned by Dimension.EncodeDimension. False is returned
+// if no Dimension with the ID exists.
+func DimensionByID(id int) (Dimension, bool) {
+	switch id {
+	case 0:
+		return Overworld, true
+	case 1:
+		return Nether, true
+	case 2:
+		return End, true
+	}
+	return nil, false
+}
+
 func (overworld) Range() cube.Range                 { return cube.Range{-64, 319} }
 func (overworld) EncodeDimension() int              { return 0 }
 func (overworld) WaterEvaporates() bool             { return false }
@@ -0,0 +1,149 @@
+package world
+
+import "strings"
+
+// lightEmissions holds the light level emitted by blocks, indexed by the name of the block without namespace. Blocks
+// not present in the map emit no light.
+var lightEmissions = map[string]uint8{
+	"beacon":                       15,
+	"conduit":                      15,
+	"end_gateway":                  15,
+	"end_portal":                   15,
+	"fire":                         15,
+	"flowing_lava":                 15,
+	"glowstone":                    15,
+	"lantern":                      15,
+	"lava":                         15,
+	"lit_pumpkin":                  15,
+	"lit_redstone_lamp":            15,
+	"ochre_froglight":              15,
+	"pearlescent_froglight":        15,
+	"sea_lantern":                  15,
+	"shroomlight":                  15,
+	"verdant_froglight":            15,
+	"end_rod":                      14,
+	"torch":                        14,
+	"cave_vines_body_with_berries": 14,
+	"cave_vines_head_with_berries": 14,
+	"lit_blast_furnace":            13,
+	"lit_furnace":                  13,
+	"lit_smoker":                   13,
+	"portal":                       11,
+	"crying_obsidian":              10,
+	"soul_fire":                    10,
+	"soul_lantern":                 10,
+	"soul_torch":                   10,
+	"lit_deepslate_redstone_ore":   9,
+	"lit_redstone_ore":             9,
+	"enchanting_table":             7,
+	"ender_chest":                  7,
+	"glow_lichen":                  7,
+	"redstone_torch":               7,
+	"amethyst_cluster":             5,
+	"large_amethyst_bud":           4,
+	"magma":                        3,
+	"medium_amethyst_bud":          2,
+	"brewing_stand":                1,
+	"brown_mushroom":               1,
+	"dragon_egg":                   1,
+	"end_portal_frame":             1,
+	"sculk_sensor":                 1,
+	"small_amethyst_bud":           1,
+	"calibrated_sculk_sensor":      1,
+	"sculk_catalyst":               6,
+	"campfire":                     15,
+	"soul_campfire":                10,
+	"underwater_torch":             14,
+	"colored_torch_red":            14,
+	"colored_torch_green":          14,
+	"colored_torch_blue":           14,
+	"colored_torch_purple":         14,
+}
+
+// lightEmission returns the light level emitted by the block state passed.
+func lightEmission(s blockState) uint8 {
+	name := strings.TrimPrefix(s.Name, "minecraft:")
+	switch name {
+	case "campfire", "soul_campfire":
+		if extinguished, _ := s.Properties["extinguished"].(uint8); extinguished != 0 {
+			return 0
+		}
+	case "respawn_anchor":
+		charge, _ := s.Properties["respawn_anchor_charge"].(int32)
+		return uint8(charge * 15 / 4)
+	case "light_block":
+		level, _ := s.Properties["block_light_level"].(int32)
+		return uint8(level)
+	case "sea_pickle":
+		if dead, _ := s.Properties["dead_bit"].(uint8); dead != 0 {
+			return 0
+		}
+		count, _ := s.Properties["cluster_count"].(int32)
+		return uint8(6 + count*3)
+	}
+	if strings.HasSuffix(name, "candle") && !strings.HasSuffix(name, "candle_cake") {
+		if lit, _ := s.Properties["lit"].(uint8); lit != 0 {
+			count, _ := s.Properties["candles"].(int32)
+			return uint8(3 + count*3)
+		}
+		return 0
+	}
+	return lightEmissions[name]
+}
+
+// lightFilter returns the amount of light levels that the block state passed filters. Solid blocks filter all light,
+// while blocks such as glass, plants and torches let light through without reducing it.
+func lightFilter(s blockState) uint8 {
+	name := strings.TrimPrefix(s.Name, "minecraft:")
+	switch name {
+	case "water", "flowing_water", "ice", "frosted_ice":
+		return 2
+	case "web", "slime", "honey_block":
+		return 1
+	case "sea_lantern", "grass":
+		return 15
+	case "frame", "glow_frame":
+		return 0
+	}
+	if strings.Contains(name, "slab") && strings.Contains(name, "double") {
+		// Double slabs, such as double_wooden_slab and mangrove_double_slab, are full blocks, unlike single slabs.
+		return 15
+	}
+	if strings.HasSuffix(name, "leaves") || strings.HasSuffix(name, "leaves2") {
+		return 1
+	}
+	for _, suffix := range transparentBlockSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return 0
+		}
+	}
+	for _, part := range transparentBlockParts {
+		if strings.Contains(name, part) {
+			return 0
+		}
+	}
+	return 15
+}
+
+var (
+	// transparentBlockSuffixes holds suffixes of the names of blocks that do not filter any light.
+	transparentBlockSuffixes = []string{
+		"air", "allium", "amethyst_bud", "amethyst_cluster", "anvil", "azalea", "azure_bluet", "banner", "barrier",
+		"bars", "beacon", "bed", "beetroot", "bell", "big_dripleaf", "brewing_stand", "bush", "button", "cactus", "cake",
+		"campfire", "candle", "carpet", "carrots", "cauldron", "chain", "chest", "cocoa", "comparator", "conduit",
+		"coral", "coral_fan", "cornflower", "dandelion", "daylight_detector", "daylight_detector_inverted",
+		"decorated_pot", "dirt_path", "door", "enchanting_table", "end_rod", "farmland", "fence", "fence_gate", "fern",
+		"fire", "flower", "flower_pot", "frog_spawn", "fungus", "glow_lichen", "grass_path", "grindstone",
+		"hanging_roots", "head", "hopper", "kelp", "ladder", "lantern", "lectern", "lever", "light_block", "lightning_rod",
+		"lilac", "lily_of_the_valley", "lily_pad", "mangrove_propagule", "melon_stem", "mushroom", "nether_wart",
+		"orchid", "oxeye_daisy", "peony", "pink_petals", "pitcher_crop", "pitcher_plant", "pointed_dripstone", "poppy",
+		"portal", "potatoes", "pressure_plate", "pumpkin_stem", "rail", "redstone_wire", "reeds", "repeater", "roots",
+		"rose_bush", "sapling", "scaffolding", "sculk_vein", "sea_pickle", "seagrass", "short_grass", "shulker_box",
+		"sign", "skull", "slab", "small_dripleaf_block", "snow_layer", "spore_blossom", "stairs", "stonecutter_block",
+		"structure_void", "sunflower", "sweet_berry_bush", "tallgrass", "torch", "torchflower", "trapdoor", "tripwire",
+		"tripwire_hook", "tulip", "vine", "vines", "wall", "waterlily", "wheat", "wither_rose",
+	}
+	// transparentBlockParts holds parts of the names of blocks that do not filter any light, regardless of where the
+	// part is found in the name.
+	transparentBlockParts = []string{"glass", "cave_vines", "double_plant"}
+)
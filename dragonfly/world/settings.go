@@ -41,6 +41,8 @@ type Settings struct {
 	// TickRange is the radius in chunks around a Viewer that has its blocks and entities ticked when the world is
 	// ticked. If set to 0, blocks and entities will never be ticked.
 	TickRange int32
+	// NetherScale is the amount of blocks in the Overworld that a single block in the Nether corresponds to.
+	NetherScale int32
 }
 
 // defaultSettings returns the default Settings for a new World.
@@ -52,5 +54,6 @@ func defaultSettings() *Settings {
 		TimeCycle:       true,
 		WeatherCycle:    true,
 		TickRange:       6,
+		NetherScale:     8,
 	}
 }
@@ -0,0 +1,28 @@
+package world
+
+// SlimeChunk checks if slimes may spawn in the chunk at the position passed, regardless of the light level. Unlike
+// Minecraft: Java Edition, Minecraft: Bedrock Edition determines slime chunks from the chunk position alone, by
+// checking if the first output of a Mersenne Twister seeded with the position is divisible by 10.
+func SlimeChunk(pos ChunkPos) bool {
+	return firstMT19937(uint32(pos.X())*0x1f1f1f1f^uint32(pos.Z()))%10 == 0
+}
+
+// firstMT19937 returns the first output of a 32-bit Mersenne Twister (MT19937) seeded with the seed passed. Only the
+// part of the state needed to produce the first output is initialised.
+func firstMT19937(seed uint32) uint32 {
+	var mt [398]uint32
+	mt[0] = seed
+	for i := uint32(1); i < 398; i++ {
+		mt[i] = 1812433253*(mt[i-1]^(mt[i-1]>>30)) + i
+	}
+	y := (mt[0] & 0x80000000) | (mt[1] & 0x7fffffff)
+	y = mt[397] ^ (y >> 1)
+	if mt[1]&1 != 0 {
+		y ^= 0x9908b0df
+	}
+	y ^= y >> 11
+	y ^= (y << 7) & 0x9d2c5680
+	y ^= (y << 15) & 0xefc60000
+	y ^= y >> 18
+	return y
+}
@@ -0,0 +1,42 @@
+package world
+
+import "testing"
+
+// TestFirstMT19937 tests that firstMT19937 returns the first output of the reference MT19937 implementation.
+func TestFirstMT19937(t *testing.T) {
+	tests := []struct {
+		seed, output uint32
+	}{
+		{seed: 0, output: 2357136044},
+		{seed: 1, output: 1791095845},
+		{seed: 5489, output: 3499211612},
+	}
+	for _, test := range tests {
+		if output := firstMT19937(test.seed); output != test.output {
+			t.Errorf("seed %v: expected first output %v, got %v", test.seed, test.output, output)
+		}
+	}
+}
+
+// TestSlimeChunk tests SlimeChunk for chunks of which it is known whether they are slime chunks.
+func TestSlimeChunk(t *testing.T) {
+	tests := []struct {
+		pos   ChunkPos
+		slime bool
+	}{
+		{pos: ChunkPos{-1, 0}, slime: true},
+		{pos: ChunkPos{0, -2}, slime: true},
+		{pos: ChunkPos{3, 1}, slime: true},
+		{pos: ChunkPos{-4, -1}, slime: true},
+		{pos: ChunkPos{6, 5}, slime: true},
+		{pos: ChunkPos{0, 0}, slime: false},
+		{pos: ChunkPos{1, 1}, slime: false},
+		{pos: ChunkPos{-1, -1}, slime: false},
+		{pos: ChunkPos{2, 0}, slime: false},
+	}
+	for _, test := range tests {
+		if slime := SlimeChunk(test.pos); slime != test.slime {
+			t.Errorf("chunk %v: expected slime chunk %v, got %v", test.pos, test.slime, slime)
+		}
+	}
+}
@@ -10,8 +10,7 @@ require (
 	github.com/google/uuid v1.3.0 // indirect
 	github.com/hajimehoshi/ebiten/v2 v2.2.4
 	github.com/klauspost/compress v1.15.1 // indirect
-	github.com/muhammadmuzzammil1998/jsonc v1.0.0 // indirect
-	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+	github.com/muhammadmuzzammil1998/jsonc v1.0.0
 	github.com/pelletier/go-toml v1.9.4
 	github.com/sandertv/go-raknet v1.11.1 // indirect
 	github.com/sandertv/gophertunnel v1.24.6
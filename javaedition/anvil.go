@@ -0,0 +1,386 @@
+package javaedition
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"reflect"
+)
+
+// anvilChunk is the NBT layout of a chunk in a region file of Java Edition 1.18 and later.
+type anvilChunk struct {
+	DataVersion   int32                    `nbt:"DataVersion"`
+	XPos          int32                    `nbt:"xPos"`
+	YPos          int32                    `nbt:"yPos"`
+	ZPos          int32                    `nbt:"zPos"`
+	Status        string                   `nbt:"Status"`
+	LastUpdate    int64                    `nbt:"LastUpdate"`
+	InhabitedTime int64                    `nbt:"InhabitedTime"`
+	IsLightOn     uint8                    `nbt:"isLightOn"`
+	Sections      []anvilSection           `nbt:"sections"`
+	BlockEntities []map[string]interface{} `nbt:"block_entities"`
+}
+
+// anvilSection is the NBT layout of a 16x16x16 section of a chunk in a region file.
+type anvilSection struct {
+	// Y is the signed Y coordinate of the section, stored in a single byte.
+	Y           uint8                  `nbt:"Y"`
+	BlockStates map[string]interface{} `nbt:"block_states"`
+	Biomes      map[string]interface{} `nbt:"biomes"`
+}
+
+// EncodeChunk converts the Bedrock chunk at the position passed, together with its block entities, to a chunk of a
+// Java region file and returns its uncompressed NBT data. Every sub chunk of the chunk becomes a section at the same
+// height, and its blocks are converted by converting the values in the palettes of the sub chunk rather than every
+// block. Heightmaps and light are left out, so that they are calculated when the chunk is first loaded.
+func (c *Converter) EncodeChunk(pos world.ChunkPos, ch *chunk.Chunk, blockEntities []map[string]interface{}) ([]byte, error) {
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	sub := ch.Sub()
+
+	data := anvilChunk{
+		DataVersion:   DataVersion,
+		XPos:          pos[0],
+		YPos:          int32(ch.Range()[0] >> 4),
+		ZPos:          pos[1],
+		Status:        "full",
+		Sections:      make([]anvilSection, 0, len(sub)),
+		BlockEntities: make([]map[string]interface{}, 0, len(blockEntities)),
+	}
+	for i, s := range sub {
+		y := ch.SubY(int16(i))
+		data.Sections = append(data.Sections, anvilSection{
+			Y:           uint8(int8(y >> 4)),
+			BlockStates: c.blockStates(s, air),
+			Biomes:      biomeSection(ch, y),
+		})
+	}
+	for _, blockEntity := range blockEntities {
+		x, _ := blockEntity["x"].(int32)
+		y, _ := blockEntity["y"].(int32)
+		z, _ := blockEntity["z"].(int32)
+		if int(y) < ch.Range().Min() || int(y) > ch.Range().Max() {
+			continue
+		}
+		bx, by, bz := uint8(x&15), int16(y), uint8(z&15)
+		block := c.lookup(ch.Block(bx, by, bz, 0), ch.Block(bx, by, bz, 1)).state
+		id, converted, ok := ConvertBlockEntity(blockEntity, block)
+		if !ok {
+			continue
+		}
+		converted["id"], converted["x"], converted["y"], converted["z"] = id, x, y, z
+		converted["keepPacked"] = uint8(0)
+		data.BlockEntities = append(data.BlockEntities, converted)
+	}
+
+	b, err := nbt.MarshalEncoding(data, nbt.BigEndian)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding chunk %v: %w", pos, err)
+	}
+	return b, nil
+}
+
+// blockStates converts the blocks of a sub chunk to the block states of a section. Every combination of values in the
+// palettes of the first and second layer of the sub chunk is converted once, after which the indices of the blocks
+// are remapped to the Java palette.
+func (c *Converter) blockStates(sub *chunk.SubChunk, air uint32) map[string]interface{} {
+	layers := sub.Layers()
+	if len(layers) == 0 {
+		return blockStateSection([]map[string]interface{}{blockStateNBT(c.lookup(air, air).state)}, nil)
+	}
+	layer0, layer1 := layers[0], (*chunk.PalettedStorage)(nil)
+	if len(layers) > 1 && (layers[1].Palette().Len() > 1 || layers[1].Palette().Value(0) != air) {
+		layer1 = layers[1]
+	}
+
+	var (
+		palette []map[string]interface{}
+		known   = make(map[string]uint16)
+		// remapped holds the Java palette index for every combination of Bedrock palette indices, with the index of
+		// the first layer in the lower 16 bits and that of the second layer in the upper 16 bits.
+		remapped = make(map[uint32]uint16)
+		unmapped = make(map[uint32]string)
+		indices  = make([]uint16, 4096)
+	)
+	for x := byte(0); x < 16; x++ {
+		for z := byte(0); z < 16; z++ {
+			for y := byte(0); y < 16; y++ {
+				key := uint32(layer0.PaletteIndex(x, y, z))
+				if layer1 != nil {
+					key |= uint32(layer1.PaletteIndex(x, y, z)) << 16
+				}
+				index, ok := remapped[key]
+				if !ok {
+					v1 := air
+					if layer1 != nil {
+						v1 = layer1.Palette().Value(uint16(key >> 16))
+					}
+					conv := c.lookup(layer0.Palette().Value(uint16(key)), v1)
+					s := conv.state.String()
+					if index, ok = known[s]; !ok {
+						index = uint16(len(palette))
+						known[s] = index
+						palette = append(palette, blockStateNBT(conv.state))
+					}
+					remapped[key], unmapped[key] = index, conv.unmapped
+				}
+				if name := unmapped[key]; name != "" {
+					c.unmapped[name]++
+				}
+				// Java sections order blocks by Y, then Z and then X.
+				indices[int(y)<<8|int(z)<<4|int(x)] = index
+			}
+		}
+	}
+	return blockStateSection(palette, indices)
+}
+
+// blockStateNBT returns the NBT of a block state as found in the palette of a section.
+func blockStateNBT(state BlockState) map[string]interface{} {
+	m := map[string]interface{}{"Name": state.Name}
+	if len(state.Properties) > 0 {
+		m["Properties"] = state.Properties
+	}
+	return m
+}
+
+// blockStateSection returns the block_states compound of a section with the palette and the indices of its blocks
+// passed. The indices are left out if the palette holds a single block state, as Java Edition does.
+func blockStateSection(palette []map[string]interface{}, indices []uint16) map[string]interface{} {
+	m := map[string]interface{}{"palette": palette}
+	if len(palette) > 1 {
+		m["data"] = packIndices(indices, bitsPerIndex(len(palette), 4))
+	}
+	return m
+}
+
+// biomeSection returns the biomes compound of the section of the chunk at the Y coordinate passed. Java Edition
+// stores a biome for every 4x4x4 cube, so the biome at the lowest corner of every cube is used.
+func biomeSection(ch *chunk.Chunk, y int16) map[string]interface{} {
+	var (
+		palette []string
+		known   = make(map[string]uint16)
+		indices = make([]uint16, 64)
+	)
+	for by := int16(0); by < 4; by++ {
+		for bz := uint8(0); bz < 4; bz++ {
+			for bx := uint8(0); bx < 4; bx++ {
+				name := Biome(ch.Biome(bx*4, y+by*4, bz*4))
+				index, ok := known[name]
+				if !ok {
+					index = uint16(len(palette))
+					known[name] = index
+					palette = append(palette, name)
+				}
+				indices[int(by)<<4|int(bz)<<2|int(bx)] = index
+			}
+		}
+	}
+	m := map[string]interface{}{"palette": palette}
+	if len(palette) > 1 {
+		m["data"] = packIndices(indices, bitsPerIndex(len(palette), 1))
+	}
+	return m
+}
+
+// bitsPerIndex returns the amount of bits needed for indices into a palette with n values, which is at least the
+// minimum passed.
+func bitsPerIndex(n, min int) int {
+	bits := min
+	for 1<<bits < n {
+		bits++
+	}
+	return bits
+}
+
+// packIndices packs the indices passed into a long array with the amount of bits per index passed. Like Java Edition,
+// indices start at the least significant bits of a long and never span two longs.
+func packIndices(indices []uint16, bits int) interface{} {
+	perLong := 64 / bits
+	longs := make([]int64, (len(indices)+perLong-1)/perLong)
+	for i, index := range indices {
+		longs[i/perLong] |= int64(index) << ((i % perLong) * bits)
+	}
+	return longArray(longs)
+}
+
+// longArray returns the int64s passed as an array, which is encoded as a TAG_Long_Array, unlike an int64 slice.
+func longArray(l []int64) interface{} {
+	arr := reflect.New(reflect.ArrayOf(len(l), reflect.TypeOf(int64(0)))).Elem()
+	reflect.Copy(arr, reflect.ValueOf(l))
+	return arr.Interface()
+}
+
+// minimumDataVersion is the data version of Java Edition 1.18, the oldest version of which chunks may be decoded.
+const minimumDataVersion = 2860
+
+// DecodeChunk decodes the uncompressed NBT data of a chunk of a Java region file, as returned by Region.Chunk, and
+// converts it to a Bedrock chunk with the cube.Range passed, together with its block entities. Sections outside the
+// range are dropped. Like EncodeChunk, the blocks of every section are converted by converting the block states in
+// its palette. Chunks saved by versions of Java Edition older than 1.18 result in an error. Chunks that are not fully
+// generated, which Java Edition generates further when they are loaded, are skipped: they are returned as a nil chunk
+// and counted in the report returned by Incomplete.
+func (c *BedrockConverter) DecodeChunk(data []byte, r cube.Range) (world.ChunkPos, *chunk.Chunk, []map[string]interface{}, error) {
+	if err := longArraysAsByteArrays(data); err != nil {
+		return world.ChunkPos{}, nil, nil, fmt.Errorf("error decoding chunk NBT: %w", err)
+	}
+	var m map[string]interface{}
+	if err := nbt.UnmarshalEncoding(data, &m, nbt.BigEndian); err != nil {
+		return world.ChunkPos{}, nil, nil, fmt.Errorf("error decoding chunk NBT: %w", err)
+	}
+	x, _ := m["xPos"].(int32)
+	z, _ := m["zPos"].(int32)
+	pos := world.ChunkPos{x, z}
+	if version, _ := m["DataVersion"].(int32); version < minimumDataVersion {
+		return pos, nil, nil, fmt.Errorf("chunk %v has data version %v and was saved by a Java Edition version older than 1.18: open and save the world in a newer version first", pos, version)
+	}
+	if status, _ := m["Status"].(string); status != "full" && status != "minecraft:full" {
+		c.incomplete++
+		return pos, nil, nil, nil
+	}
+
+	ch := chunk.New(c.air, r)
+	sections, _ := m["sections"].([]interface{})
+	for _, s := range sections {
+		section, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sectionY, _ := section["Y"].(uint8)
+		y := int(int8(sectionY)) << 4
+		if y < r.Min() || y > r.Max() {
+			continue
+		}
+		if states, ok := section["block_states"].(map[string]interface{}); ok {
+			if err := c.decodeBlockStates(ch, int16(y), states); err != nil {
+				return pos, nil, nil, fmt.Errorf("error decoding blocks of chunk %v: %w", pos, err)
+			}
+		}
+		if biomes, ok := section["biomes"].(map[string]interface{}); ok {
+			if err := decodeBiomes(ch, int16(y), biomes); err != nil {
+				return pos, nil, nil, fmt.Errorf("error decoding biomes of chunk %v: %w", pos, err)
+			}
+		}
+	}
+
+	list, _ := m["block_entities"].([]interface{})
+	blockEntities := make([]map[string]interface{}, 0, len(list))
+	for _, entry := range list {
+		blockEntity, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if y, _ := blockEntity["y"].(int32); int(y) < r.Min() || int(y) > r.Max() {
+			continue
+		}
+		if converted, ok := ToBedrockBlockEntity(blockEntity); ok {
+			blockEntities = append(blockEntities, converted)
+		}
+	}
+	return pos, ch, blockEntities, nil
+}
+
+// decodeBlockStates converts the block_states compound of a section and sets its blocks in the sub chunk of the
+// chunk at the Y coordinate passed.
+func (c *BedrockConverter) decodeBlockStates(ch *chunk.Chunk, y int16, states map[string]interface{}) error {
+	list, _ := states["palette"].([]interface{})
+	if len(list) == 0 {
+		return fmt.Errorf("section has no block palette")
+	}
+	palette := make([]bedrockBlock, len(list))
+	for i, entry := range list {
+		m, _ := entry.(map[string]interface{})
+		name, _ := m["Name"].(string)
+		state := BlockState{Name: name, Properties: make(map[string]string)}
+		properties, _ := m["Properties"].(map[string]interface{})
+		for k, v := range properties {
+			state.Properties[k], _ = v.(string)
+		}
+		palette[i] = c.lookup(state)
+	}
+	indices, err := unpackIndices(states["data"], 4096, bitsPerIndex(len(palette), 4), len(palette))
+	if err != nil {
+		return err
+	}
+
+	sub := ch.SubChunk(y)
+	for i, index := range indices {
+		b := palette[index]
+		if b.unmapped != "" {
+			c.unmapped[b.unmapped]++
+		}
+		if b.layer0 == c.air {
+			continue
+		}
+		// Java sections order blocks by Y, then Z and then X.
+		bx, by, bz := byte(i&15), byte(i>>8), byte((i>>4)&15)
+		sub.SetBlock(bx, by, bz, 0, b.layer0)
+		if b.layer1 != c.air {
+			sub.SetBlock(bx, by, bz, 1, b.layer1)
+		}
+	}
+	return nil
+}
+
+// decodeBiomes converts the biomes compound of a section and sets the biomes of the chunk in the section at the Y
+// coordinate passed.
+func decodeBiomes(ch *chunk.Chunk, y int16, biomes map[string]interface{}) error {
+	list, _ := biomes["palette"].([]interface{})
+	if len(list) == 0 {
+		return fmt.Errorf("section has no biome palette")
+	}
+	palette := make([]uint32, len(list))
+	for i, entry := range list {
+		name, _ := entry.(string)
+		palette[i] = BedrockBiome(name)
+	}
+	indices, err := unpackIndices(biomes["data"], 64, bitsPerIndex(len(palette), 1), len(palette))
+	if err != nil {
+		return err
+	}
+	for i, index := range indices {
+		// Every biome covers a cube of 4x4x4 blocks.
+		bx, by, bz := uint8(i&3)*4, int16(i>>4)*4, uint8((i>>2)&3)*4
+		for dx := uint8(0); dx < 4; dx++ {
+			for dz := uint8(0); dz < 4; dz++ {
+				for dy := int16(0); dy < 4; dy++ {
+					ch.SetBiome(bx+dx, y+by+dy, bz+dz, palette[index])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// unpackIndices unpacks n indices with the amount of bits per index passed from the long array passed, which is
+// packed as done by packIndices and decoded as a byte array by longArraysAsByteArrays. If the palette holds a single
+// value, the long array is absent and all indices are 0. Indices that point outside the palette of the length passed
+// result in an error.
+func unpackIndices(data interface{}, n, bits, paletteLen int) ([]uint16, error) {
+	indices := make([]uint16, n)
+	if paletteLen == 1 {
+		return indices, nil
+	}
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Array || v.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, fmt.Errorf("section has palette of %v values but no index data", paletteLen)
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+
+	perLong := 64 / bits
+	if len(b) < (n+perLong-1)/perLong*8 {
+		return nil, fmt.Errorf("section has %v longs of index data, expected %v", len(b)/8, (n+perLong-1)/perLong)
+	}
+	mask := uint64(1)<<bits - 1
+	for i := range indices {
+		index := binary.BigEndian.Uint64(b[i/perLong*8:]) >> ((i % perLong) * bits) & mask
+		if int(index) >= paletteLen {
+			return nil, fmt.Errorf("section has index %v outside its palette of %v values", index, paletteLen)
+		}
+		indices[i] = uint16(index)
+	}
+	return indices, nil
+}
@@ -0,0 +1,74 @@
+package javaedition
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// TestPackIndices tests that indices packed by packIndices use the bits per index and amount of longs of Java Edition,
+// and that unpackIndices returns the same indices from them.
+func TestPackIndices(t *testing.T) {
+	tests := []struct {
+		name          string
+		n, paletteLen int
+		min, bits     int
+		longs         int
+	}{
+		{name: "blocks, 2 states", n: 4096, paletteLen: 2, min: 4, bits: 4, longs: 256},
+		{name: "blocks, 16 states", n: 4096, paletteLen: 16, min: 4, bits: 4, longs: 256},
+		{name: "blocks, 17 states", n: 4096, paletteLen: 17, min: 4, bits: 5, longs: 342},
+		{name: "blocks, 33 states", n: 4096, paletteLen: 33, min: 4, bits: 6, longs: 410},
+		{name: "blocks, 300 states", n: 4096, paletteLen: 300, min: 4, bits: 9, longs: 586},
+		{name: "biomes, 2 biomes", n: 64, paletteLen: 2, min: 1, bits: 1, longs: 1},
+		{name: "biomes, 3 biomes", n: 64, paletteLen: 3, min: 1, bits: 2, longs: 2},
+		{name: "biomes, 5 biomes", n: 64, paletteLen: 5, min: 1, bits: 3, longs: 4},
+		{name: "biomes, 64 biomes", n: 64, paletteLen: 64, min: 1, bits: 6, longs: 7},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bits := bitsPerIndex(test.paletteLen, test.min)
+			if bits != test.bits {
+				t.Fatalf("expected %v bits per index, got %v", test.bits, bits)
+			}
+			indices := make([]uint16, test.n)
+			for i := range indices {
+				indices[i] = uint16((i * 7) % test.paletteLen)
+			}
+			longs := reflect.ValueOf(packIndices(indices, bits))
+			if longs.Len() != test.longs {
+				t.Fatalf("expected %v longs, got %v", test.longs, longs.Len())
+			}
+
+			unpacked, err := unpackIndices(decodedLongArray(longs), test.n, bits, test.paletteLen)
+			if err != nil {
+				t.Fatalf("error unpacking indices: %v", err)
+			}
+			if !reflect.DeepEqual(unpacked, indices) {
+				t.Fatalf("unpacked indices differ from packed indices")
+			}
+		})
+	}
+}
+
+// TestPackIndicesLayout tests that indices are packed starting at the least significant bits of a long.
+func TestPackIndicesLayout(t *testing.T) {
+	indices := make([]uint16, 16)
+	for i := range indices {
+		indices[i] = uint16(i)
+	}
+	longs := packIndices(indices, 4).([1]int64)
+	if uint64(longs[0]) != 0xfedcba9876543210 {
+		t.Fatalf("expected packed long %#x, got %#x", uint64(0xfedcba9876543210), uint64(longs[0]))
+	}
+}
+
+// decodedLongArray returns the long array passed as it is decoded from NBT by longArraysAsByteArrays: a byte array
+// holding the longs in big endian order.
+func decodedLongArray(longs reflect.Value) interface{} {
+	b := make([]byte, longs.Len()*8)
+	for i := 0; i < longs.Len(); i++ {
+		binary.BigEndian.PutUint64(b[i*8:], uint64(longs.Index(i).Int()))
+	}
+	return byteArray(b)
+}
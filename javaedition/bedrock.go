@@ -0,0 +1,164 @@
+package javaedition
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"strings"
+	"sync"
+)
+
+// bedrockState is the runtime ID of a Bedrock block state together with the properties of the Java block state that
+// it converts to.
+type bedrockState struct {
+	rid        uint32
+	properties map[string]string
+}
+
+var (
+	// bedrockStatesOnce makes sure bedrockStates is only filled once.
+	bedrockStatesOnce sync.Once
+	// bedrockStates maps the names of Java blocks to all Bedrock block states that convert to a block with that name,
+	// in the order of their runtime IDs.
+	bedrockStates map[string][]bedrockState
+)
+
+// loadBedrockStates fills bedrockStates by converting every Bedrock block state to Java Edition.
+func loadBedrockStates() {
+	bedrockStates = make(map[string][]bedrockState)
+	for rid := uint32(0); ; rid++ {
+		name, properties, ok := chunk.RuntimeIDToState(rid)
+		if !ok {
+			break
+		}
+		state, ok := FromBedrock(name, properties)
+		if !ok || state.Name == air.Name && name != "minecraft:air" {
+			continue
+		}
+		delete(state.Properties, "waterlogged")
+		bedrockStates[state.Name] = append(bedrockStates[state.Name], bedrockState{rid: rid, properties: state.Properties})
+	}
+}
+
+// javaAliases maps the names of Java blocks that have no Bedrock equivalent to the Java block that is closest to it
+// and does. Most of these are the lower parts of plants, which Bedrock Edition does not distinguish.
+var javaAliases = map[string]string{
+	"minecraft:cave_air":              "minecraft:air",
+	"minecraft:void_air":              "minecraft:air",
+	"minecraft:kelp_plant":            "minecraft:kelp",
+	"minecraft:twisting_vines_plant":  "minecraft:twisting_vines",
+	"minecraft:weeping_vines_plant":   "minecraft:weeping_vines",
+	"minecraft:attached_melon_stem":   "minecraft:melon_stem",
+	"minecraft:attached_pumpkin_stem": "minecraft:pumpkin_stem",
+}
+
+// javaAliasSuffixes holds the suffixes of the names of Java blocks whose colour or type is stored in a block entity
+// in Bedrock Edition, together with the Java block that they are converted as.
+var javaAliasSuffixes = []struct{ suffix, name string }{
+	{"_wall_banner", "minecraft:white_wall_banner"},
+	{"_banner", "minecraft:white_banner"},
+	{"_bed", "minecraft:white_bed"},
+	{"_wall_head", "minecraft:skeleton_wall_skull"},
+	{"_wall_skull", "minecraft:skeleton_wall_skull"},
+	{"_head", "minecraft:skeleton_skull"},
+	{"_skull", "minecraft:skeleton_skull"},
+}
+
+// implicitlyWaterlogged holds the names of Java blocks that are always in water, but that have no waterlogged
+// property.
+var implicitlyWaterlogged = map[string]struct{}{
+	"minecraft:seagrass": {}, "minecraft:tall_seagrass": {}, "minecraft:kelp": {}, "minecraft:kelp_plant": {},
+	"minecraft:bubble_column": {},
+}
+
+// ToBedrock converts a Java block state to the runtime IDs of the Bedrock blocks on the first and second layer that
+// make it up. The second layer holds water for waterlogged blocks, and air otherwise. The Bedrock block state chosen
+// is the one that converts back to the block with the most properties in common with the block state passed, so that
+// Java properties that Bedrock Edition does not store, such as the connections of fences, are ignored. False is
+// returned if no Bedrock block converts to a block with the name of the block state.
+func ToBedrock(state BlockState) (layer0, layer1 uint32, ok bool) {
+	bedrockStatesOnce.Do(loadBedrockStates)
+
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	layer1 = air
+	_, waterlogged := implicitlyWaterlogged[state.Name]
+	if state.Properties["waterlogged"] == "true" || waterlogged {
+		layer1, _ = chunk.StateToRuntimeID("minecraft:water", map[string]interface{}{"liquid_depth": int32(0)})
+	}
+
+	name := state.Name
+	candidates, ok := bedrockStates[name]
+	if !ok {
+		if alias, aliased := javaAliases[name]; aliased {
+			name = alias
+		}
+		for _, s := range javaAliasSuffixes {
+			if strings.HasSuffix(name, s.suffix) {
+				name = s.name
+				break
+			}
+		}
+		if candidates, ok = bedrockStates[name]; !ok {
+			return air, air, false
+		}
+	}
+	best, bestScore := candidates[0].rid, -1
+	for _, candidate := range candidates {
+		score := 0
+		for k, v := range candidate.properties {
+			if state.Properties[k] == v {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = candidate.rid, score
+		}
+	}
+	return best, layer1, true
+}
+
+// BedrockConverter converts the block states of Java chunks to Bedrock runtime IDs. The result is cached for every
+// block state, and block states that could not be converted are counted by name.
+type BedrockConverter struct {
+	states   map[string]bedrockBlock
+	unmapped map[string]int
+	// incomplete is the amount of chunks skipped because they were not fully generated.
+	incomplete int
+	air        uint32
+}
+
+// bedrockBlock is the cached result of converting a Java block state to Bedrock Edition.
+type bedrockBlock struct {
+	layer0, layer1 uint32
+	// unmapped is the name of the Java block if it could not be converted.
+	unmapped string
+}
+
+// NewBedrockConverter returns a new BedrockConverter with an empty cache.
+func NewBedrockConverter() *BedrockConverter {
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	return &BedrockConverter{states: make(map[string]bedrockBlock), unmapped: make(map[string]int), air: air}
+}
+
+// lookup returns the cached result of converting the Java block state passed, converting it first if it was not yet
+// cached.
+func (c *BedrockConverter) lookup(state BlockState) bedrockBlock {
+	key := state.String()
+	b, ok := c.states[key]
+	if !ok {
+		if b.layer0, b.layer1, ok = ToBedrock(state); !ok {
+			b.unmapped = state.Name
+		}
+		c.states[key] = b
+	}
+	return b
+}
+
+// Unmapped returns the names of the Java blocks that could not be converted, together with the amount of blocks with
+// each name that were replaced with air.
+func (c *BedrockConverter) Unmapped() map[string]int {
+	return c.unmapped
+}
+
+// Incomplete returns the amount of chunks skipped by DecodeChunk because they were not fully generated.
+func (c *BedrockConverter) Incomplete() int {
+	return c.incomplete
+}
@@ -0,0 +1,135 @@
+package javaedition
+
+import "strings"
+
+// biomes maps the numerical IDs of Bedrock biomes to the names of the Java biomes they are converted to. Java
+// Edition 1.18 merged many of the hill and mutated variants of biomes into their base biome, so several Bedrock
+// biomes share a Java biome. Java Edition has no deep warm ocean, and cherry groves were added after the Java version
+// that is converted to, so the closest biome is used for them.
+var biomes = map[uint32]string{
+	0:   "ocean",
+	1:   "plains",
+	2:   "desert",
+	3:   "windswept_hills",
+	4:   "forest",
+	5:   "taiga",
+	6:   "swamp",
+	7:   "river",
+	8:   "nether_wastes",
+	9:   "the_end",
+	10:  "frozen_ocean",
+	11:  "frozen_river",
+	12:  "snowy_plains",
+	13:  "snowy_plains",
+	14:  "mushroom_fields",
+	15:  "mushroom_fields",
+	16:  "beach",
+	17:  "desert",
+	18:  "forest",
+	19:  "taiga",
+	20:  "windswept_hills",
+	21:  "jungle",
+	22:  "jungle",
+	23:  "sparse_jungle",
+	24:  "deep_ocean",
+	25:  "stony_shore",
+	26:  "snowy_beach",
+	27:  "birch_forest",
+	28:  "birch_forest",
+	29:  "dark_forest",
+	30:  "snowy_taiga",
+	31:  "snowy_taiga",
+	32:  "old_growth_pine_taiga",
+	33:  "old_growth_pine_taiga",
+	34:  "windswept_forest",
+	35:  "savanna",
+	36:  "savanna_plateau",
+	37:  "badlands",
+	38:  "wooded_badlands",
+	39:  "badlands",
+	40:  "warm_ocean",
+	41:  "warm_ocean",
+	42:  "lukewarm_ocean",
+	43:  "deep_lukewarm_ocean",
+	44:  "cold_ocean",
+	45:  "deep_cold_ocean",
+	46:  "frozen_ocean",
+	47:  "deep_frozen_ocean",
+	48:  "bamboo_jungle",
+	49:  "bamboo_jungle",
+	129: "sunflower_plains",
+	130: "desert",
+	131: "windswept_gravelly_hills",
+	132: "flower_forest",
+	133: "taiga",
+	134: "swamp",
+	140: "ice_spikes",
+	149: "jungle",
+	151: "sparse_jungle",
+	155: "old_growth_birch_forest",
+	156: "old_growth_birch_forest",
+	157: "dark_forest",
+	158: "snowy_taiga",
+	160: "old_growth_spruce_taiga",
+	161: "old_growth_spruce_taiga",
+	162: "windswept_gravelly_hills",
+	163: "windswept_savanna",
+	164: "windswept_savanna",
+	165: "eroded_badlands",
+	166: "wooded_badlands",
+	167: "badlands",
+	178: "soul_sand_valley",
+	179: "crimson_forest",
+	180: "warped_forest",
+	181: "basalt_deltas",
+	182: "jagged_peaks",
+	183: "frozen_peaks",
+	184: "snowy_slopes",
+	185: "grove",
+	186: "meadow",
+	187: "lush_caves",
+	188: "dripstone_caves",
+	189: "stony_peaks",
+	190: "deep_dark",
+	191: "mangrove_swamp",
+	192: "meadow",
+}
+
+// Biome returns the namespaced name of the Java biome that the Bedrock biome with the numerical ID passed is
+// converted to. Unknown biomes are converted to plains.
+func Biome(id uint32) string {
+	if name, ok := biomes[id]; ok {
+		return "minecraft:" + name
+	}
+	return "minecraft:plains"
+}
+
+// bedrockBiomes maps the names of Java biomes to the numerical IDs of the Bedrock biomes they are converted to. It
+// holds the Bedrock biome with the lowest ID for every Java biome in biomes, and the Java biomes that have no Bedrock
+// equivalent.
+var bedrockBiomes = map[string]uint32{
+	"cherry_grove":      192,
+	"end_highlands":     9,
+	"end_midlands":      9,
+	"small_end_islands": 9,
+	"end_barrens":       9,
+	"the_void":          1,
+}
+
+// init fills bedrockBiomes with the reverse of biomes.
+func init() {
+	for id, name := range biomes {
+		if existing, ok := bedrockBiomes[name]; !ok || id < existing {
+			bedrockBiomes[name] = id
+		}
+	}
+}
+
+// BedrockBiome returns the numerical ID of the Bedrock biome that the Java biome with the namespaced name passed is
+// converted to. Unknown biomes are converted to plains.
+func BedrockBiome(name string) uint32 {
+	if id, ok := bedrockBiomes[strings.TrimPrefix(name, "minecraft:")]; ok {
+		return id
+	}
+	return 1
+}
@@ -0,0 +1,155 @@
+package javaedition
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"sort"
+	"strings"
+)
+
+// DataVersion is the data version of Minecraft: Java Edition 1.19, the version that blocks are converted to.
+const DataVersion = 3105
+
+// BlockState is a block state of Minecraft: Java Edition, made up of a name and a set of properties.
+type BlockState struct {
+	// Name is the namespaced name of the block, such as minecraft:oak_stairs.
+	Name string
+	// Properties holds the properties of the block state. Properties that are not set take their default value.
+	Properties map[string]string
+}
+
+// String returns the block state in the format used by Java Edition, such as
+// minecraft:oak_stairs[facing=east,half=bottom]. Properties are sorted by their name.
+func (s BlockState) String() string {
+	if len(s.Properties) == 0 {
+		return s.Name
+	}
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(s.Name)
+	b.WriteByte('[')
+	for i, k := range keys {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k + "=" + s.Properties[k])
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// ParseBlockState parses a block state in the format returned by BlockState.String. Names without a namespace are
+// given the minecraft namespace.
+func ParseBlockState(s string) (BlockState, error) {
+	name, props := s, ""
+	if i := strings.IndexByte(s, '['); i != -1 {
+		if !strings.HasSuffix(s, "]") {
+			return BlockState{}, fmt.Errorf("block state %q has unterminated properties", s)
+		}
+		name, props = s[:i], s[i+1:len(s)-1]
+	}
+	if !strings.Contains(name, ":") {
+		name = "minecraft:" + name
+	}
+	state := BlockState{Name: name, Properties: make(map[string]string)}
+	if props == "" {
+		return state, nil
+	}
+	for _, prop := range strings.Split(props, ",") {
+		k, v, ok := strings.Cut(prop, "=")
+		if !ok {
+			return BlockState{}, fmt.Errorf("block state %q has invalid property %q", s, prop)
+		}
+		state.Properties[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return state, nil
+}
+
+// air is the Java block state of air, which blocks that cannot be converted are replaced with.
+var air = BlockState{Name: "minecraft:air"}
+
+// FromBedrock converts a Bedrock block state with the name and properties passed to a Java block state. False is
+// returned if the block has no Java equivalent or is not in the translation table.
+func FromBedrock(name string, properties map[string]interface{}) (BlockState, bool) {
+	name = strings.TrimPrefix(name, "minecraft:")
+	if f, ok := blocks[name]; ok {
+		return f(props(properties)), true
+	}
+	for _, family := range families {
+		if base := strings.TrimSuffix(name, family.suffix); base != name {
+			return family.convert(base, props(properties)), true
+		}
+	}
+	return BlockState{}, false
+}
+
+// Converter converts the runtime IDs of the blocks of a chunk or structure to Java block states. The result is cached
+// for every combination of runtime IDs, and blocks that could not be converted are counted by name.
+type Converter struct {
+	states   map[[2]uint32]converted
+	unmapped map[string]int
+}
+
+// converted is the cached result of converting a block.
+type converted struct {
+	state BlockState
+	// unmapped is the name of the Bedrock block if it could not be converted.
+	unmapped string
+}
+
+// NewConverter returns a new Converter with an empty cache.
+func NewConverter() *Converter {
+	return &Converter{states: make(map[[2]uint32]converted), unmapped: make(map[string]int)}
+}
+
+// Block returns the Java block state of the block made up of the runtime IDs on the first and second layer passed.
+// If the second layer holds water, the block is waterlogged if the Java block supports it. Blocks that could not be
+// converted are returned as air and recorded in the report returned by Unmapped.
+func (c *Converter) Block(layer0, layer1 uint32) BlockState {
+	conv := c.lookup(layer0, layer1)
+	if conv.unmapped != "" {
+		c.unmapped[conv.unmapped]++
+	}
+	return conv.state
+}
+
+// lookup returns the cached result of converting the block made up of the runtime IDs passed, converting it first if
+// it was not yet cached. Unlike Block, lookup does not record blocks that could not be converted.
+func (c *Converter) lookup(layer0, layer1 uint32) converted {
+	key := [2]uint32{layer0, layer1}
+	conv, ok := c.states[key]
+	if !ok {
+		conv = c.convert(layer0, layer1)
+		c.states[key] = conv
+	}
+	return conv
+}
+
+// convert converts the block made up of the runtime IDs passed without using the cache.
+func (c *Converter) convert(layer0, layer1 uint32) converted {
+	name, properties, found := chunk.RuntimeIDToState(layer0)
+	if !found {
+		return converted{state: air, unmapped: fmt.Sprintf("unknown runtime ID %v", layer0)}
+	}
+	state, ok := FromBedrock(name, properties)
+	if !ok {
+		return converted{state: air, unmapped: name}
+	}
+	if _, waterloggable := state.Properties["waterlogged"]; waterloggable {
+		if name, _, _ := chunk.RuntimeIDToState(layer1); name == "minecraft:water" || name == "minecraft:flowing_water" {
+			state.Properties["waterlogged"] = "true"
+		}
+	}
+	return converted{state: state}
+}
+
+// Unmapped returns the names of the Bedrock blocks that could not be converted, together with the amount of blocks
+// with each name that were replaced with air.
+func (c *Converter) Unmapped() map[string]int {
+	return c.unmapped
+}
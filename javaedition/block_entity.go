@@ -0,0 +1,329 @@
+package javaedition
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// containers holds the names of the Java blocks holding items, which are also the IDs of their block entities.
+var containers = map[string]struct{}{
+	"minecraft:chest": {}, "minecraft:trapped_chest": {}, "minecraft:barrel": {}, "minecraft:furnace": {},
+	"minecraft:blast_furnace": {}, "minecraft:smoker": {}, "minecraft:hopper": {}, "minecraft:dispenser": {},
+	"minecraft:dropper": {}, "minecraft:brewing_stand": {},
+}
+
+// ConvertBlockEntity converts the NBT data of a Bedrock block entity to that of a Java block entity, for the Java
+// block state passed that the block entity belongs to. Signs and containers are supported, and false is returned
+// for all other block entities. The ID of the Java block entity is returned separately, and the converted data holds
+// neither the ID nor the position of the block entity.
+func ConvertBlockEntity(data map[string]interface{}, block BlockState) (id string, converted map[string]interface{}, ok bool) {
+	if strings.HasSuffix(block.Name, "_sign") {
+		return "minecraft:sign", convertSign(data), true
+	}
+	id = block.Name
+	_, ok = containers[id]
+	if strings.HasSuffix(block.Name, "shulker_box") {
+		// Shulker boxes of all colours share the same block entity.
+		id, ok = "minecraft:shulker_box", true
+	}
+	if !ok {
+		return "", nil, false
+	}
+
+	converted = map[string]interface{}{"Items": convertItems(data["Items"])}
+	if customName, ok := data["CustomName"].(string); ok && customName != "" {
+		converted["CustomName"] = textComponent(customName)
+	}
+	switch id {
+	case "minecraft:furnace", "minecraft:blast_furnace", "minecraft:smoker":
+		// The cooking progress is stored under the same names, but the total cooking time is not stored by Bedrock
+		// Edition, so the default cooking time of the block is used.
+		converted["BurnTime"], _ = data["BurnTime"].(int16)
+		converted["CookTime"], _ = data["CookTime"].(int16)
+		converted["CookTimeTotal"] = int16(200)
+		if id != "minecraft:furnace" {
+			converted["CookTimeTotal"] = int16(100)
+		}
+	case "minecraft:brewing_stand":
+		converted["BrewTime"], _ = data["CookTime"].(int16)
+		fuel, _ := data["FuelAmount"].(int16)
+		converted["Fuel"] = uint8(fuel)
+	}
+	return id, converted, true
+}
+
+// convertItems converts a list of Bedrock items with their slots to a list of Java items. Items keep their Bedrock
+// name, which is the same as the Java name for most items.
+func convertItems(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, entry := range list {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := item["Name"].(string)
+		count, _ := item["Count"].(uint8)
+		if name == "" || count == 0 {
+			continue
+		}
+		slot, _ := item["Slot"].(uint8)
+		converted := map[string]interface{}{"id": name, "Count": count, "Slot": slot}
+		if tag, ok := item["tag"].(map[string]interface{}); ok {
+			if display := convertDisplay(tag); display != nil {
+				converted["tag"] = map[string]interface{}{"display": display}
+			}
+		}
+		items = append(items, converted)
+	}
+	return items
+}
+
+// convertDisplay converts the custom name and lore of a Bedrock item, which are plain text, to the text components
+// used by Java items. Nil is returned if the item has neither.
+func convertDisplay(tag map[string]interface{}) map[string]interface{} {
+	display, ok := tag["display"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	converted := make(map[string]interface{})
+	if name, ok := display["Name"].(string); ok {
+		converted["Name"] = textComponent(name)
+	}
+	if lore, ok := display["Lore"].([]interface{}); ok {
+		lines := make([]string, 0, len(lore))
+		for _, line := range lore {
+			if s, ok := line.(string); ok {
+				lines = append(lines, textComponent(s))
+			}
+		}
+		converted["Lore"] = lines
+	}
+	if len(converted) == 0 {
+		return nil
+	}
+	return converted
+}
+
+// signColours holds the colours of sign text in Bedrock Edition for every dye, as stored in the SignTextColor field.
+var signColours = map[string]uint32{
+	"white": 0xf0f0f0, "orange": 0xf9801d, "magenta": 0xc74ebd, "light_blue": 0x3ab3da, "yellow": 0xfed83d,
+	"lime": 0x80c71f, "pink": 0xf38baa, "gray": 0x474f52, "light_gray": 0x9d9d97, "cyan": 0x169c9c,
+	"purple": 0x8932b8, "blue": 0x3c44aa, "brown": 0x835432, "green": 0x5e7c16, "red": 0xb02e26, "black": 0x000000,
+}
+
+// convertSign converts the NBT data of a Bedrock sign, which holds all lines of text in a single field, to that of a
+// Java sign, which holds every line as a text component.
+func convertSign(data map[string]interface{}) map[string]interface{} {
+	text, _ := data["Text"].(string)
+	lines := strings.Split(text, "\n")
+
+	converted := make(map[string]interface{})
+	for i := 0; i < 4; i++ {
+		line := ""
+		if i < len(lines) {
+			line = lines[i]
+		}
+		converted["Text"+string(rune('1'+i))] = textComponent(line)
+	}
+
+	colour, ok := data["SignTextColor"].(int32)
+	if !ok {
+		colour = -16777216
+	}
+	converted["Color"] = nearestDye(uint32(colour) & 0xffffff)
+	glowing, _ := data["IgnoreLighting"].(uint8)
+	converted["GlowingText"] = glowing
+	return converted
+}
+
+// nearestDye returns the name of the dye with the sign text colour closest to the RGB colour passed.
+func nearestDye(rgb uint32) string {
+	best, bestDist := "black", -1
+	for name, c := range signColours {
+		dr := int(rgb>>16&0xff) - int(c>>16&0xff)
+		dg := int(rgb>>8&0xff) - int(c>>8&0xff)
+		db := int(rgb&0xff) - int(c&0xff)
+		if dist := dr*dr + dg*dg + db*db; bestDist == -1 || dist < bestDist || (dist == bestDist && name < best) {
+			best, bestDist = name, dist
+		}
+	}
+	return best
+}
+
+// textComponent returns the JSON text component holding the plain text passed.
+func textComponent(s string) string {
+	data, _ := json.Marshal(struct {
+		Text string `json:"text"`
+	}{Text: s})
+	return string(data)
+}
+
+// bedrockBlockEntities maps the IDs of Java block entities to those of the Bedrock block entities they are converted
+// to.
+var bedrockBlockEntities = map[string]string{
+	"minecraft:sign": "Sign", "minecraft:chest": "Chest", "minecraft:trapped_chest": "Chest",
+	"minecraft:barrel": "Barrel", "minecraft:furnace": "Furnace", "minecraft:blast_furnace": "BlastFurnace",
+	"minecraft:smoker": "Smoker", "minecraft:hopper": "Hopper", "minecraft:dispenser": "Dispenser",
+	"minecraft:dropper": "Dropper", "minecraft:brewing_stand": "BrewingStand", "minecraft:shulker_box": "ShulkerBox",
+}
+
+// ToBedrockBlockEntity converts the NBT data of a Java block entity, including its ID and position, to that of a
+// Bedrock block entity. Signs and containers are supported, and false is returned for all other block entities.
+func ToBedrockBlockEntity(data map[string]interface{}) (map[string]interface{}, bool) {
+	javaID, _ := data["id"].(string)
+	id, ok := bedrockBlockEntities[javaID]
+	if !ok {
+		return nil, false
+	}
+	converted := map[string]interface{}{"id": id, "x": data["x"], "y": data["y"], "z": data["z"], "isMovable": uint8(1)}
+	if id == "Sign" {
+		convertJavaSign(data, converted)
+		return converted, true
+	}
+
+	converted["Items"] = convertJavaItems(data["Items"])
+	if customName, ok := data["CustomName"].(string); ok && customName != "" {
+		converted["CustomName"] = plainText(customName)
+	}
+	switch id {
+	case "Furnace", "BlastFurnace", "Smoker":
+		converted["BurnTime"], _ = data["BurnTime"].(int16)
+		converted["CookTime"], _ = data["CookTime"].(int16)
+		converted["BurnDuration"], _ = data["BurnTime"].(int16)
+	case "BrewingStand":
+		converted["CookTime"], _ = data["BrewTime"].(int16)
+		fuel, _ := data["Fuel"].(uint8)
+		converted["FuelAmount"] = int16(fuel)
+		converted["FuelTotal"] = int16(20)
+	}
+	return converted, true
+}
+
+// convertJavaItems converts a list of Java items with their slots to a list of Bedrock items. Items keep their Java
+// name, which is the same as the Bedrock name for most items.
+func convertJavaItems(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, entry := range list {
+		item, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := item["id"].(string)
+		count, _ := item["Count"].(uint8)
+		if name == "" || count == 0 {
+			continue
+		}
+		slot, _ := item["Slot"].(uint8)
+		converted := map[string]interface{}{"Name": name, "Count": count, "Slot": slot, "Damage": int16(0), "WasPickedUp": uint8(0)}
+		if tag, ok := item["tag"].(map[string]interface{}); ok {
+			if display := convertJavaDisplay(tag); display != nil {
+				converted["tag"] = map[string]interface{}{"display": display}
+			}
+		}
+		items = append(items, converted)
+	}
+	return items
+}
+
+// convertJavaDisplay converts the custom name and lore of a Java item, which are text components, to the plain text
+// used by Bedrock items. Nil is returned if the item has neither.
+func convertJavaDisplay(tag map[string]interface{}) map[string]interface{} {
+	display, ok := tag["display"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	converted := make(map[string]interface{})
+	if name, ok := display["Name"].(string); ok {
+		converted["Name"] = plainText(name)
+	}
+	if lore, ok := display["Lore"].([]interface{}); ok {
+		lines := make([]string, 0, len(lore))
+		for _, line := range lore {
+			if s, ok := line.(string); ok {
+				lines = append(lines, plainText(s))
+			}
+		}
+		converted["Lore"] = lines
+	}
+	if len(converted) == 0 {
+		return nil
+	}
+	return converted
+}
+
+// convertJavaSign converts the NBT data of a Java sign to that of a Bedrock sign and stores it in the map passed.
+// Both the four text fields of signs up to Java Edition 1.19 and the front text of later signs are supported.
+func convertJavaSign(data, converted map[string]interface{}) {
+	var (
+		lines    = make([]string, 0, 4)
+		colour   = "black"
+		glowing  uint8
+		messages []interface{}
+	)
+	if front, ok := data["front_text"].(map[string]interface{}); ok {
+		messages, _ = front["messages"].([]interface{})
+		if c, ok := front["color"].(string); ok {
+			colour = c
+		}
+		glowing, _ = front["has_glowing_text"].(uint8)
+	} else {
+		for i := 0; i < 4; i++ {
+			messages = append(messages, data["Text"+string(rune('1'+i))])
+		}
+		if c, ok := data["Color"].(string); ok {
+			colour = c
+		}
+		glowing, _ = data["GlowingText"].(uint8)
+	}
+	for _, message := range messages {
+		s, _ := message.(string)
+		lines = append(lines, plainText(s))
+	}
+	// Trailing empty lines are left out, as Bedrock Edition does not store them.
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	rgb, ok := signColours[colour]
+	if !ok {
+		rgb = signColours["black"]
+	}
+	converted["Text"] = strings.Join(lines, "\n")
+	converted["TextOwner"] = ""
+	converted["SignTextColor"] = int32(rgb | 0xff000000)
+	converted["IgnoreLighting"] = glowing
+}
+
+// plainText returns the plain text held by the JSON text component passed, including the text of its extra
+// components. Text that is not valid JSON is returned as is.
+func plainText(component string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(component), &v); err != nil {
+		return component
+	}
+	var b strings.Builder
+	writePlainText(&b, v)
+	return b.String()
+}
+
+// writePlainText writes the plain text of a decoded JSON text component to the strings.Builder passed.
+func writePlainText(b *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		b.WriteString(v)
+	case []interface{}:
+		for _, c := range v {
+			writePlainText(b, c)
+		}
+	case map[string]interface{}:
+		if text, ok := v["text"].(string); ok {
+			b.WriteString(text)
+		}
+		if extra, ok := v["extra"].([]interface{}); ok {
+			for _, c := range extra {
+				writePlainText(b, c)
+			}
+		}
+	}
+}
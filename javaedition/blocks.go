@@ -0,0 +1,1074 @@
+package javaedition
+
+import (
+	"strconv"
+	"strings"
+)
+
+// props wraps the properties of a Bedrock block state, so that they may be read as a specific type.
+type props map[string]interface{}
+
+// int returns the property with the name passed as an int.
+func (p props) int(k string) int {
+	switch v := p[k].(type) {
+	case int32:
+		return int(v)
+	case uint8:
+		return int(v)
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
+
+// bool returns the property with the name passed as a string holding true or false.
+func (p props) bool(k string) string {
+	return strconv.FormatBool(p.int(k) != 0)
+}
+
+// str returns the property with the name passed as a string.
+func (p props) str(k string) string {
+	v, _ := p[k].(string)
+	return v
+}
+
+// state returns a Java block state with the name passed, in the minecraft namespace, and the properties passed as
+// alternating keys and values.
+func state(name string, properties ...string) BlockState {
+	s := BlockState{Name: "minecraft:" + name, Properties: make(map[string]string, len(properties)/2)}
+	for i := 0; i+1 < len(properties); i += 2 {
+		s.Properties[properties[i]] = properties[i+1]
+	}
+	return s
+}
+
+// index returns the value at the index passed, or the first value if the index is out of bounds.
+func index(values []string, i int) string {
+	if i < 0 || i >= len(values) {
+		return values[0]
+	}
+	return values[i]
+}
+
+var (
+	// horizontal holds the directions in the order of the direction property of most Bedrock blocks.
+	horizontal = []string{"south", "west", "north", "east"}
+	// weirdo holds the directions in the order of the weirdo_direction property of stairs and the direction property
+	// of trapdoors.
+	weirdo = []string{"east", "west", "south", "north"}
+	// doorDirections holds the directions in the order of the direction property of doors.
+	doorDirections = []string{"east", "south", "west", "north"}
+	// faces holds the faces in the order of the facing_direction property.
+	faces = []string{"down", "up", "north", "south", "west", "east"}
+	// opposite maps every face to its opposite face.
+	opposite = map[string]string{"down": "up", "up": "down", "north": "south", "south": "north", "west": "east", "east": "west"}
+	// railShapes holds the shapes of rails in the order of the rail_direction property.
+	railShapes = []string{"north_south", "east_west", "ascending_east", "ascending_west", "ascending_north", "ascending_south", "south_east", "south_west", "north_west", "north_east"}
+	// wallHeights maps the values of the wall_connection_type properties to the heights of Java walls.
+	wallHeights = map[string]string{"none": "none", "short": "low", "tall": "tall"}
+)
+
+// colour returns the Java name of a colour from the color property of a Bedrock block.
+func colour(p props) string {
+	if c := p.str("color"); c != "silver" {
+		return c
+	}
+	return "light_gray"
+}
+
+// horizontalFace returns the horizontal direction held in the facing_direction property, defaulting to north for
+// the vertical faces.
+func horizontalFace(p props) string {
+	if f := p.int("facing_direction"); f >= 2 && f <= 5 {
+		return faces[f]
+	}
+	return "north"
+}
+
+// fixed returns a converter always returning a Java block state with the name and properties passed.
+func fixed(name string, properties ...string) func(p props) BlockState {
+	return func(props) BlockState {
+		return state(name, properties...)
+	}
+}
+
+// pillar returns a converter for blocks with an axis, such as logs.
+func pillar(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, "axis", axis(p))
+	}
+}
+
+// axis returns the axis held in the pillar_axis property.
+func axis(p props) string {
+	if a := p.str("pillar_axis"); a != "" {
+		return a
+	}
+	return "y"
+}
+
+// variant returns a converter for Bedrock blocks that hold the variant of the block in a property, with each value of
+// the property mapping to a different Java block.
+func variant(property string, names map[string]string) func(p props) BlockState {
+	return func(p props) BlockState {
+		name, ok := names[p.str(property)]
+		if !ok {
+			// The property holds a value not in the table, so fall back to the first variant in alphabetical order to
+			// get the same result every time.
+			for _, n := range names {
+				if !ok || n < name {
+					name, ok = n, true
+				}
+			}
+		}
+		return state(name)
+	}
+}
+
+// pattern returns a converter for Bedrock blocks that hold the variant of the block in a property, with the Java name
+// being the pattern passed with %v replaced by the value of the property.
+func pattern(property, format string, value func(p props) string) func(p props) BlockState {
+	return func(p props) BlockState {
+		v := p.str(property)
+		if value != nil {
+			v = value(p)
+		}
+		return state(strings.ReplaceAll(format, "%v", v))
+	}
+}
+
+// age returns a converter for blocks that grow, with the Java age being the value of the Bedrock property passed,
+// capped at the maximum age passed.
+func age(name, property string, max int) func(p props) BlockState {
+	return func(p props) BlockState {
+		a := p.int(property)
+		if a > max {
+			a = max
+		}
+		return state(name, "age", strconv.Itoa(a))
+	}
+}
+
+// facing returns a converter for blocks that face in one of six directions, held in the facing_direction property.
+func facing(name string, properties ...string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, append([]string{"facing", index(faces, p.int("facing_direction"))}, properties...)...)
+	}
+}
+
+// facingHorizontal returns a converter for blocks that face in one of four directions, held in the facing_direction
+// property.
+func facingHorizontal(name string, properties ...string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, append([]string{"facing", horizontalFace(p)}, properties...)...)
+	}
+}
+
+// direction returns a converter for blocks that face in one of four directions, held in the direction property.
+func direction(name string, properties ...string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, append([]string{"facing", index(horizontal, p.int("direction"))}, properties...)...)
+	}
+}
+
+// with returns a converter that calls the converter passed and sets the properties returned by the function passed.
+func with(f func(p props) BlockState, properties func(p props) []string) func(p props) BlockState {
+	return func(p props) BlockState {
+		s := f(p)
+		extra := properties(p)
+		for i := 0; i+1 < len(extra); i += 2 {
+			s.Properties[extra[i]] = extra[i+1]
+		}
+		return s
+	}
+}
+
+// identicalBlocks holds the names of blocks that have the same name in Bedrock and Java Edition, and have no
+// properties or only properties that are not relevant to Java Edition.
+var identicalBlocks = []string{
+	"air", "amethyst_block", "ancient_debris", "azalea", "barrier", "beacon", "bedrock", "blackstone", "blue_ice",
+	"bookshelf", "brown_mushroom", "budding_amethyst", "calcite", "cartography_table", "chiseled_deepslate",
+	"chiseled_nether_bricks", "chiseled_polished_blackstone", "chorus_plant", "clay", "coal_block", "coal_ore",
+	"cobbled_deepslate", "cobblestone", "conduit", "copper_block", "copper_ore", "cracked_deepslate_bricks",
+	"cracked_deepslate_tiles", "cracked_nether_bricks", "cracked_polished_blackstone_bricks", "crafting_table",
+	"crimson_fence", "crimson_fungus", "crimson_nylium", "crimson_planks", "crimson_roots", "crying_obsidian",
+	"cut_copper", "deepslate_bricks", "deepslate_coal_ore", "deepslate_copper_ore", "deepslate_diamond_ore",
+	"deepslate_emerald_ore", "deepslate_gold_ore", "deepslate_iron_ore", "deepslate_lapis_ore", "deepslate_tiles",
+	"diamond_block", "diamond_ore", "dragon_egg", "dried_kelp_block", "dripstone_block", "emerald_block",
+	"emerald_ore", "enchanting_table", "end_gateway", "end_portal", "end_stone", "exposed_copper",
+	"exposed_cut_copper", "fletching_table", "flower_pot", "flowering_azalea", "frog_spawn", "gilded_blackstone",
+	"glass", "glass_pane", "glowstone", "gold_block", "gold_ore", "gravel", "hanging_roots", "honey_block",
+	"honeycomb_block", "ice", "iron_bars", "iron_block", "iron_ore", "jukebox", "lapis_block", "lapis_ore",
+	"lodestone", "mangrove_fence", "mangrove_planks", "mangrove_roots", "moss_block", "moss_carpet",
+	"mossy_cobblestone", "mud", "mud_bricks", "mycelium", "nether_brick_fence", "nether_gold_ore", "nether_sprouts",
+	"nether_wart_block", "netherite_block", "netherrack", "obsidian", "oxidized_copper", "oxidized_cut_copper",
+	"packed_ice", "packed_mud", "podzol", "polished_blackstone", "polished_blackstone_bricks", "polished_deepslate",
+	"powder_snow", "quartz_bricks", "raw_copper_block", "raw_gold_block", "raw_iron_block", "red_mushroom",
+	"redstone_block", "reinforced_deepslate", "sculk", "sea_lantern", "shroomlight", "smithing_table",
+	"smooth_basalt", "smooth_stone", "soul_fire", "soul_sand", "soul_soil", "spore_blossom", "structure_void",
+	"target", "tinted_glass", "tuff", "warped_fence", "warped_fungus", "warped_nylium", "warped_planks",
+	"warped_roots", "warped_wart_block", "waxed_copper", "waxed_cut_copper", "waxed_exposed_copper",
+	"waxed_exposed_cut_copper", "waxed_oxidized_copper", "waxed_oxidized_cut_copper", "waxed_weathered_copper",
+	"waxed_weathered_cut_copper", "weathered_copper", "weathered_cut_copper", "wither_rose",
+}
+
+// pillarBlocks holds the names of blocks that have the same name in Bedrock and Java Edition and have an axis.
+var pillarBlocks = []string{
+	"basalt", "bone_block", "chain", "crimson_hyphae", "crimson_stem", "deepslate", "hay_block",
+	"infested_deepslate", "mangrove_log", "muddy_mangrove_roots", "ochre_froglight", "pearlescent_froglight",
+	"polished_basalt", "stripped_acacia_log", "stripped_birch_log", "stripped_crimson_hyphae", "stripped_crimson_stem",
+	"stripped_dark_oak_log", "stripped_jungle_log", "stripped_mangrove_log", "stripped_mangrove_wood",
+	"stripped_oak_log", "stripped_spruce_log", "stripped_warped_hyphae", "stripped_warped_stem", "verdant_froglight",
+	"warped_hyphae", "warped_stem",
+}
+
+// renamedBlocks maps the names of Bedrock blocks without relevant properties to the names of their Java equivalent.
+var renamedBlocks = map[string]string{
+	"brick_block":        "bricks",
+	"deadbush":           "dead_bush",
+	"dirt_with_roots":    "rooted_dirt",
+	"end_bricks":         "end_stone_bricks",
+	"grass_path":         "dirt_path",
+	"hardened_clay":      "terracotta",
+	"magma":              "magma_block",
+	"melon_block":        "melon",
+	"mob_spawner":        "spawner",
+	"nether_brick":       "nether_bricks",
+	"noteblock":          "note_block",
+	"quartz_ore":         "nether_quartz_ore",
+	"red_nether_brick":   "red_nether_bricks",
+	"slime":              "slime_block",
+	"snow":               "snow_block",
+	"stonecutter":        "stonecutter",
+	"undyed_shulker_box": "shulker_box",
+	"waterlily":          "lily_pad",
+	"web":                "cobweb",
+	"yellow_flower":      "dandelion",
+}
+
+// colouredBlocks maps the names of Bedrock blocks with a color property to the name of their Java equivalent, with
+// %v being replaced by the colour.
+var colouredBlocks = map[string]string{
+	"carpet":                "%v_carpet",
+	"concrete":              "%v_concrete",
+	"concrete_powder":       "%v_concrete_powder",
+	"shulker_box":           "%v_shulker_box",
+	"stained_glass":         "%v_stained_glass",
+	"stained_glass_pane":    "%v_stained_glass_pane",
+	"stained_hardened_clay": "%v_terracotta",
+	"wool":                  "%v_wool",
+}
+
+// coralTypes maps the values of the coral_color property to the names of Java coral types.
+var coralTypes = map[string]string{"blue": "tube", "pink": "brain", "purple": "bubble", "red": "fire", "yellow": "horn"}
+
+// stoneSlabTypes maps the values of the stone_slab_type properties to the names of Java slabs.
+var stoneSlabTypes = map[string]string{
+	"brick": "brick_slab", "cobblestone": "cobblestone_slab", "nether_brick": "nether_brick_slab",
+	"quartz": "quartz_slab", "sandstone": "sandstone_slab", "smooth_stone": "smooth_stone_slab",
+	"stone_brick": "stone_brick_slab", "wood": "petrified_oak_slab",
+	"mossy_cobblestone": "mossy_cobblestone_slab", "prismarine_brick": "prismarine_brick_slab",
+	"prismarine_dark": "dark_prismarine_slab", "prismarine_rough": "prismarine_slab", "purpur": "purpur_slab",
+	"red_nether_brick": "red_nether_brick_slab", "red_sandstone": "red_sandstone_slab",
+	"smooth_sandstone": "smooth_sandstone_slab",
+	"andesite":         "andesite_slab", "diorite": "diorite_slab", "end_stone_brick": "end_stone_brick_slab",
+	"granite": "granite_slab", "polished_andesite": "polished_andesite_slab",
+	"polished_diorite": "polished_diorite_slab", "polished_granite": "polished_granite_slab",
+	"smooth_red_sandstone": "smooth_red_sandstone_slab",
+	"cut_red_sandstone":    "cut_red_sandstone_slab", "cut_sandstone": "cut_sandstone_slab",
+	"mossy_stone_brick": "mossy_stone_brick_slab", "smooth_quartz": "smooth_quartz_slab", "stone": "stone_slab",
+}
+
+// stoneSlab returns a converter for the Bedrock stone slabs that hold their type in the property passed.
+func stoneSlab(property string, double bool) func(p props) BlockState {
+	return func(p props) BlockState {
+		name, ok := stoneSlabTypes[p.str(property)]
+		if !ok {
+			name = "stone_slab"
+		}
+		return state(name, "type", slabType(p, double), "waterlogged", "false")
+	}
+}
+
+// slabType returns the Java type of slab from the top_slot_bit property.
+func slabType(p props, double bool) string {
+	if double {
+		return "double"
+	} else if p.int("top_slot_bit") != 0 {
+		return "top"
+	}
+	return "bottom"
+}
+
+// mushroomSides holds the sides of a mushroom block that show the cap or stem texture for each value of the
+// huge_mushroom_bits property, in the order north, east, south, west, up, down.
+var mushroomSides = map[int][6]bool{
+	0:  {},
+	1:  {true, false, false, true, true, false},
+	2:  {true, false, false, false, true, false},
+	3:  {true, true, false, false, true, false},
+	4:  {false, false, false, true, true, false},
+	5:  {false, false, false, false, true, false},
+	6:  {false, true, false, false, true, false},
+	7:  {false, false, true, true, true, false},
+	8:  {false, false, true, false, true, false},
+	9:  {false, true, true, false, true, false},
+	10: {true, true, true, true, false, false},
+}
+
+// mushroomBlock returns a converter for huge mushroom blocks with the Java name passed.
+func mushroomBlock(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		bits, n := p.int("huge_mushroom_bits"), name
+		sides, ok := mushroomSides[bits]
+		if !ok {
+			sides = [6]bool{true, true, true, true, true, true}
+		}
+		if bits == 10 || bits == 15 {
+			n = "mushroom_stem"
+		}
+		var properties []string
+		for i, side := range []string{"north", "east", "south", "west", "up", "down"} {
+			properties = append(properties, side, strconv.FormatBool(sides[i]))
+		}
+		return state(n, properties...)
+	}
+}
+
+// torch returns a converter for torches, which are converted to the standing or wall variant depending on the
+// torch_facing_direction property. In Bedrock, the property holds the side of the torch that it is attached to.
+func torch(standing, wall string, properties ...string) func(p props) BlockState {
+	return func(p props) BlockState {
+		f := p.str("torch_facing_direction")
+		if f == "top" || f == "unknown" || f == "" {
+			return state(standing, properties...)
+		}
+		return state(wall, append([]string{"facing", opposite[f]}, properties...)...)
+	}
+}
+
+// rail returns a converter for rails, which have their shape held in the rail_direction property.
+func rail(name string, powerable bool) func(p props) BlockState {
+	return func(p props) BlockState {
+		s := state(name, "shape", index(railShapes, p.int("rail_direction")), "waterlogged", "false")
+		if powerable {
+			s.Properties["powered"] = p.bool("rail_data_bit")
+		}
+		return s
+	}
+}
+
+// faceBits returns the Java properties for blocks that may be on multiple faces of a block, such as vines, from a
+// Bedrock property holding one bit per face. The sides passed are in the order of the bits.
+func faceBits(p props, property string, sides ...string) []string {
+	bits := p.int(property)
+	properties := make([]string, 0, len(sides)*2)
+	for i, side := range sides {
+		properties = append(properties, side, strconv.FormatBool(bits&(1<<i) != 0))
+	}
+	return properties
+}
+
+// cauldron converts a Bedrock cauldron, which is a single block with the liquid held in a property, to one of the
+// Java cauldron blocks.
+func cauldron(p props) BlockState {
+	level := strconv.Itoa((p.int("fill_level") + 1) / 2)
+	switch {
+	case p.int("fill_level") == 0:
+		return state("cauldron")
+	case p.str("cauldron_liquid") == "lava":
+		return state("lava_cauldron")
+	case p.str("cauldron_liquid") == "powder_snow":
+		return state("powder_snow_cauldron", "level", level)
+	}
+	return state("water_cauldron", "level", level)
+}
+
+// blocks holds the converters of all Bedrock blocks that are converted by their exact name.
+var blocks = map[string]func(p props) BlockState{
+	"stone": variant("stone_type", map[string]string{
+		"stone": "stone", "granite": "granite", "granite_smooth": "polished_granite", "diorite": "diorite",
+		"diorite_smooth": "polished_diorite", "andesite": "andesite", "andesite_smooth": "polished_andesite",
+	}),
+	"dirt":          variant("dirt_type", map[string]string{"normal": "dirt", "coarse": "coarse_dirt"}),
+	"sand":          variant("sand_type", map[string]string{"normal": "sand", "red": "red_sand"}),
+	"sandstone":     variant("sand_stone_type", map[string]string{"default": "sandstone", "heiroglyphs": "chiseled_sandstone", "cut": "cut_sandstone", "smooth": "smooth_sandstone"}),
+	"red_sandstone": variant("sand_stone_type", map[string]string{"default": "red_sandstone", "heiroglyphs": "chiseled_red_sandstone", "cut": "cut_red_sandstone", "smooth": "smooth_red_sandstone"}),
+	"prismarine":    variant("prismarine_block_type", map[string]string{"default": "prismarine", "bricks": "prismarine_bricks", "dark": "dark_prismarine"}),
+	"stonebrick":    variant("stone_brick_type", map[string]string{"default": "stone_bricks", "mossy": "mossy_stone_bricks", "cracked": "cracked_stone_bricks", "chiseled": "chiseled_stone_bricks", "smooth": "stone_bricks"}),
+	"sponge":        variant("sponge_type", map[string]string{"dry": "sponge", "wet": "wet_sponge"}),
+	"tallgrass":     variant("tall_grass_type", map[string]string{"default": "grass", "tall": "grass", "fern": "fern", "snow": "grass"}),
+	"red_flower": variant("flower_type", map[string]string{
+		"poppy": "poppy", "orchid": "blue_orchid", "allium": "allium", "houstonia": "azure_bluet",
+		"tulip_red": "red_tulip", "tulip_orange": "orange_tulip", "tulip_white": "white_tulip",
+		"tulip_pink": "pink_tulip", "oxeye": "oxeye_daisy", "cornflower": "cornflower",
+		"lily_of_the_valley": "lily_of_the_valley",
+	}),
+	"monster_egg": variant("monster_egg_stone_type", map[string]string{
+		"stone": "infested_stone", "cobblestone": "infested_cobblestone", "stone_brick": "infested_stone_bricks",
+		"mossy_stone_brick": "infested_mossy_stone_bricks", "cracked_stone_brick": "infested_cracked_stone_bricks",
+		"chiseled_stone_brick": "infested_chiseled_stone_bricks",
+	}),
+	"planks": pattern("wood_type", "%v_planks", nil),
+	"fence":  pattern("wood_type", "%v_fence", nil),
+	"coral": pattern("coral_color", "%v_coral", func(p props) string {
+		if p.int("dead_bit") != 0 {
+			return "dead_" + coralTypes[p.str("coral_color")]
+		}
+		return coralTypes[p.str("coral_color")]
+	}),
+	"coral_block": pattern("coral_color", "%v_coral_block", func(p props) string {
+		if p.int("dead_bit") != 0 {
+			return "dead_" + coralTypes[p.str("coral_color")]
+		}
+		return coralTypes[p.str("coral_color")]
+	}),
+	"coral_fan":       pattern("coral_color", "%v_coral_fan", func(p props) string { return coralTypes[p.str("coral_color")] }),
+	"coral_fan_dead":  pattern("coral_color", "dead_%v_coral_fan", func(p props) string { return coralTypes[p.str("coral_color")] }),
+	"coral_fan_hang":  coralWallFan("tube", "brain"),
+	"coral_fan_hang2": coralWallFan("bubble", "fire"),
+	"coral_fan_hang3": coralWallFan("horn", "horn"),
+
+	"log": func(p props) BlockState {
+		return state(p.str("old_log_type")+"_log", "axis", axis(p))
+	},
+	"log2": func(p props) BlockState {
+		return state(p.str("new_log_type")+"_log", "axis", axis(p))
+	},
+	"wood": func(p props) BlockState {
+		if p.int("stripped_bit") != 0 {
+			return state("stripped_"+p.str("wood_type")+"_wood", "axis", axis(p))
+		}
+		return state(p.str("wood_type")+"_wood", "axis", axis(p))
+	},
+	"mangrove_wood": func(p props) BlockState {
+		if p.int("stripped_bit") != 0 {
+			return state("stripped_mangrove_wood", "axis", axis(p))
+		}
+		return state("mangrove_wood", "axis", axis(p))
+	},
+	"leaves": func(p props) BlockState {
+		return state(p.str("old_leaf_type")+"_leaves", "persistent", p.bool("persistent_bit"), "distance", "7")
+	},
+	"leaves2": func(p props) BlockState {
+		return state(p.str("new_leaf_type")+"_leaves", "persistent", p.bool("persistent_bit"), "distance", "7")
+	},
+	"azalea_leaves": func(p props) BlockState {
+		return state("azalea_leaves", "persistent", p.bool("persistent_bit"), "distance", "7")
+	},
+	"azalea_leaves_flowered": func(p props) BlockState {
+		return state("flowering_azalea_leaves", "persistent", p.bool("persistent_bit"), "distance", "7")
+	},
+	"mangrove_leaves": func(p props) BlockState {
+		return state("mangrove_leaves", "persistent", p.bool("persistent_bit"), "distance", "7", "waterlogged", "false")
+	},
+	"sapling": func(p props) BlockState {
+		return state(p.str("sapling_type")+"_sapling", "stage", strconv.Itoa(p.int("age_bit")))
+	},
+	"bamboo_sapling": fixed("bamboo_sapling"),
+	"bamboo": func(p props) BlockState {
+		leaves := map[string]string{"no_leaves": "none", "small_leaves": "small", "large_leaves": "large"}[p.str("bamboo_leaf_size")]
+		a := "0"
+		if p.str("bamboo_stalk_thickness") == "thick" {
+			a = "1"
+		}
+		return state("bamboo", "leaves", leaves, "age", a, "stage", strconv.Itoa(p.int("age_bit")))
+	},
+
+	"wooden_slab": func(p props) BlockState {
+		return state(p.str("wood_type")+"_slab", "type", slabType(p, false), "waterlogged", "false")
+	},
+	"double_wooden_slab": func(p props) BlockState {
+		return state(p.str("wood_type")+"_slab", "type", "double", "waterlogged", "false")
+	},
+	"stone_block_slab":         stoneSlab("stone_slab_type", false),
+	"stone_block_slab2":        stoneSlab("stone_slab_type_2", false),
+	"stone_block_slab3":        stoneSlab("stone_slab_type_3", false),
+	"stone_block_slab4":        stoneSlab("stone_slab_type_4", false),
+	"double_stone_block_slab":  stoneSlab("stone_slab_type", true),
+	"double_stone_block_slab2": stoneSlab("stone_slab_type_2", true),
+	"double_stone_block_slab3": stoneSlab("stone_slab_type_3", true),
+	"double_stone_block_slab4": stoneSlab("stone_slab_type_4", true),
+	"cobblestone_wall": func(p props) BlockState {
+		t := p.str("wall_block_type")
+		if t == "end_brick" {
+			t = "end_stone_brick"
+		}
+		return wall(t, p)
+	},
+
+	"purpur_block": func(p props) BlockState {
+		if p.str("chisel_type") == "lines" {
+			return state("purpur_pillar", "axis", axis(p))
+		}
+		return state("purpur_block")
+	},
+	"quartz_block": func(p props) BlockState {
+		switch p.str("chisel_type") {
+		case "lines":
+			return state("quartz_pillar", "axis", axis(p))
+		case "chiseled":
+			return state("chiseled_quartz_block")
+		case "smooth":
+			return state("smooth_quartz")
+		}
+		return state("quartz_block")
+	},
+	"double_plant": func(p props) BlockState {
+		name := map[string]string{
+			"sunflower": "sunflower", "syringa": "lilac", "grass": "tall_grass", "fern": "large_fern",
+			"rose": "rose_bush", "paeonia": "peony",
+		}[p.str("double_plant_type")]
+		if name == "" {
+			name = "tall_grass"
+		}
+		return state(name, "half", half(p))
+	},
+	"seagrass": func(p props) BlockState {
+		switch p.str("sea_grass_type") {
+		case "double_bot":
+			return state("tall_seagrass", "half", "lower")
+		case "double_top":
+			return state("tall_seagrass", "half", "upper")
+		}
+		return state("seagrass")
+	},
+	"grass": fixed("grass_block", "snowy", "false"),
+	"bed": with(direction("red_bed"), func(p props) []string {
+		part := "foot"
+		if p.int("head_piece_bit") != 0 {
+			part = "head"
+		}
+		return []string{"part", part, "occupied", p.bool("occupied_bit")}
+	}),
+	"skull":       skull,
+	"frosted_ice": age("frosted_ice", "age", 3),
+
+	"torch":                torch("torch", "wall_torch"),
+	"soul_torch":           torch("soul_torch", "soul_wall_torch"),
+	"redstone_torch":       torch("redstone_torch", "redstone_wall_torch", "lit", "true"),
+	"unlit_redstone_torch": torch("redstone_torch", "redstone_wall_torch", "lit", "false"),
+	"lever": func(p props) BlockState {
+		face, f := "wall", p.str("lever_direction")
+		switch f {
+		case "down_east_west", "down_north_south":
+			face = "ceiling"
+		case "up_east_west", "up_north_south":
+			face = "floor"
+		}
+		switch f {
+		case "down_east_west", "up_east_west":
+			f = "east"
+		case "down_north_south", "up_north_south":
+			f = "north"
+		}
+		return state("lever", "face", face, "facing", f, "powered", p.bool("open_bit"))
+	},
+	"rail":           rail("rail", false),
+	"golden_rail":    rail("powered_rail", true),
+	"detector_rail":  rail("detector_rail", true),
+	"activator_rail": rail("activator_rail", true),
+
+	"furnace":           facingHorizontal("furnace", "lit", "false"),
+	"lit_furnace":       facingHorizontal("furnace", "lit", "true"),
+	"blast_furnace":     facingHorizontal("blast_furnace", "lit", "false"),
+	"lit_blast_furnace": facingHorizontal("blast_furnace", "lit", "true"),
+	"smoker":            facingHorizontal("smoker", "lit", "false"),
+	"lit_smoker":        facingHorizontal("smoker", "lit", "true"),
+	"chest":             facingHorizontal("chest", "type", "single", "waterlogged", "false"),
+	"trapped_chest":     facingHorizontal("trapped_chest", "type", "single", "waterlogged", "false"),
+	"ender_chest":       facingHorizontal("ender_chest", "waterlogged", "false"),
+	"ladder":            facingHorizontal("ladder", "waterlogged", "false"),
+	"wall_banner":       facingHorizontal("white_wall_banner"),
+	"stonecutter_block": facingHorizontal("stonecutter"),
+	"barrel": with(facing("barrel"), func(p props) []string {
+		return []string{"open", p.bool("open_bit")}
+	}),
+	"hopper": func(p props) BlockState {
+		f := index(faces, p.int("facing_direction"))
+		if f == "up" {
+			f = "down"
+		}
+		return state("hopper", "facing", f, "enabled", strconv.FormatBool(p.int("toggle_bit") == 0))
+	},
+	"dispenser": with(facing("dispenser"), func(p props) []string {
+		return []string{"triggered", p.bool("triggered_bit")}
+	}),
+	"dropper": with(facing("dropper"), func(p props) []string {
+		return []string{"triggered", p.bool("triggered_bit")}
+	}),
+	"observer": with(facing("observer"), func(p props) []string {
+		return []string{"powered", p.bool("powered_bit")}
+	}),
+	"piston":                      facing("piston", "extended", "false"),
+	"sticky_piston":               facing("sticky_piston", "extended", "false"),
+	"piston_arm_collision":        facing("piston_head", "type", "normal", "short", "false"),
+	"sticky_piston_arm_collision": facing("piston_head", "type", "sticky", "short", "false"),
+	"end_rod":                     facing("end_rod"),
+	"lightning_rod":               facing("lightning_rod", "powered", "false", "waterlogged", "false"),
+	"amethyst_cluster":            facing("amethyst_cluster", "waterlogged", "false"),
+	"large_amethyst_bud":          facing("large_amethyst_bud", "waterlogged", "false"),
+	"medium_amethyst_bud":         facing("medium_amethyst_bud", "waterlogged", "false"),
+	"small_amethyst_bud":          facing("small_amethyst_bud", "waterlogged", "false"),
+	"command_block": with(facing("command_block"), func(p props) []string {
+		return []string{"conditional", p.bool("conditional_bit")}
+	}),
+	"chain_command_block": with(facing("chain_command_block"), func(p props) []string {
+		return []string{"conditional", p.bool("conditional_bit")}
+	}),
+	"repeating_command_block": with(facing("repeating_command_block"), func(p props) []string {
+		return []string{"conditional", p.bool("conditional_bit")}
+	}),
+
+	"pumpkin":        fixed("pumpkin"),
+	"carved_pumpkin": direction("carved_pumpkin"),
+	"lit_pumpkin":    direction("jack_o_lantern"),
+	"loom":           direction("loom"),
+	"lectern": with(direction("lectern", "has_book", "false"), func(p props) []string {
+		return []string{"powered", p.bool("powered_bit")}
+	}),
+	"beehive": with(direction("beehive"), func(p props) []string {
+		return []string{"honey_level", strconv.Itoa(p.int("honey_level"))}
+	}),
+	"bee_nest": with(direction("bee_nest"), func(p props) []string {
+		return []string{"honey_level", strconv.Itoa(p.int("honey_level"))}
+	}),
+	"campfire": with(direction("campfire", "signal_fire", "false", "waterlogged", "false"), func(p props) []string {
+		return []string{"lit", strconv.FormatBool(p.int("extinguished") == 0)}
+	}),
+	"soul_campfire": with(direction("soul_campfire", "signal_fire", "false", "waterlogged", "false"), func(p props) []string {
+		return []string{"lit", strconv.FormatBool(p.int("extinguished") == 0)}
+	}),
+	"cocoa": with(direction("cocoa"), func(p props) []string {
+		return []string{"age", strconv.Itoa(p.int("age"))}
+	}),
+	"end_portal_frame": with(direction("end_portal_frame"), func(p props) []string {
+		return []string{"eye", p.bool("end_portal_eye_bit")}
+	}),
+	"tripwire_hook": with(direction("tripwire_hook"), func(p props) []string {
+		return []string{"attached", p.bool("attached_bit"), "powered", p.bool("powered_bit")}
+	}),
+	"trip_wire": func(p props) BlockState {
+		return state("tripwire", "attached", p.bool("attached_bit"), "disarmed", p.bool("disarmed_bit"), "powered", p.bool("powered_bit"))
+	},
+	"unpowered_repeater": with(direction("repeater", "powered", "false", "locked", "false"), func(p props) []string {
+		return []string{"delay", strconv.Itoa(p.int("repeater_delay") + 1)}
+	}),
+	"powered_repeater": with(direction("repeater", "powered", "true", "locked", "false"), func(p props) []string {
+		return []string{"delay", strconv.Itoa(p.int("repeater_delay") + 1)}
+	}),
+	"unpowered_comparator": with(direction("comparator", "powered", "false"), comparatorMode),
+	"powered_comparator":   with(direction("comparator", "powered", "true"), comparatorMode),
+	"anvil": func(p props) BlockState {
+		name := map[string]string{"slightly_damaged": "chipped_anvil", "very_damaged": "damaged_anvil", "broken": "damaged_anvil"}[p.str("damage")]
+		if name == "" {
+			name = "anvil"
+		}
+		return state(name, "facing", index(horizontal, p.int("direction")))
+	},
+	"bell": func(p props) BlockState {
+		attachment := map[string]string{"standing": "floor", "hanging": "ceiling", "side": "single_wall", "multiple": "double_wall"}[p.str("attachment")]
+		return state("bell", "attachment", attachment, "facing", index(horizontal, p.int("direction")), "powered", "false")
+	},
+	"grindstone": func(p props) BlockState {
+		face := map[string]string{"standing": "floor", "hanging": "ceiling", "side": "wall", "multiple": "wall"}[p.str("attachment")]
+		return state("grindstone", "face", face, "facing", index(horizontal, p.int("direction")))
+	},
+	"big_dripleaf": func(p props) BlockState {
+		f := index(horizontal, p.int("direction"))
+		if p.int("big_dripleaf_head") == 0 {
+			return state("big_dripleaf_stem", "facing", f, "waterlogged", "false")
+		}
+		tilt := map[string]string{"none": "none", "unstable": "unstable", "partial_tilt": "partial", "full_tilt": "full"}[p.str("big_dripleaf_tilt")]
+		return state("big_dripleaf", "facing", f, "tilt", tilt, "waterlogged", "false")
+	},
+	"small_dripleaf_block": func(p props) BlockState {
+		return state("small_dripleaf", "facing", index(horizontal, p.int("direction")), "half", half(p), "waterlogged", "false")
+	},
+
+	"wheat":            age("wheat", "growth", 7),
+	"carrots":          age("carrots", "growth", 7),
+	"potatoes":         age("potatoes", "growth", 7),
+	"melon_stem":       age("melon_stem", "growth", 7),
+	"pumpkin_stem":     age("pumpkin_stem", "growth", 7),
+	"sweet_berry_bush": age("sweet_berry_bush", "growth", 3),
+	"nether_wart":      age("nether_wart", "age", 3),
+	"cactus":           age("cactus", "age", 15),
+	"reeds":            age("sugar_cane", "age", 15),
+	"fire":             age("fire", "age", 15),
+	"chorus_flower":    age("chorus_flower", "age", 5),
+	"kelp":             age("kelp", "kelp_age", 25),
+	"weeping_vines":    age("weeping_vines", "weeping_vines_age", 25),
+	"twisting_vines":   age("twisting_vines", "twisting_vines_age", 25),
+	"beetroot": func(p props) BlockState {
+		return state("beetroots", "age", strconv.Itoa(p.int("growth")/2))
+	},
+	"cave_vines": with(age("cave_vines", "growing_plant_age", 25), func(props) []string {
+		return []string{"berries", "false"}
+	}),
+	"cave_vines_head_with_berries": with(age("cave_vines", "growing_plant_age", 25), func(props) []string {
+		return []string{"berries", "true"}
+	}),
+	"cave_vines_body_with_berries": fixed("cave_vines_plant", "berries", "true"),
+	"mangrove_propagule": func(p props) BlockState {
+		return state("mangrove_propagule", "stage", strconv.Itoa(p.int("propagule_stage")), "hanging", p.bool("hanging"), "age", "0", "waterlogged", "false")
+	},
+
+	"farmland": func(p props) BlockState {
+		return state("farmland", "moisture", strconv.Itoa(p.int("moisturized_amount")))
+	},
+	"snow_layer": func(p props) BlockState {
+		return state("snow", "layers", strconv.Itoa(p.int("height")+1))
+	},
+	"cake": func(p props) BlockState {
+		return state("cake", "bites", strconv.Itoa(p.int("bite_counter")))
+	},
+	"composter": func(p props) BlockState {
+		return state("composter", "level", strconv.Itoa(p.int("composter_fill_level")))
+	},
+	"cauldron":      cauldron,
+	"lava_cauldron": cauldron,
+	"water":         liquid("water"),
+	"flowing_water": liquid("water"),
+	"lava":          liquid("lava"),
+	"flowing_lava":  liquid("lava"),
+	"bubble_column": func(p props) BlockState {
+		return state("bubble_column", "drag", p.bool("drag_down"))
+	},
+	"scaffolding": func(p props) BlockState {
+		return state("scaffolding", "distance", strconv.Itoa(p.int("stability")), "bottom", "false", "waterlogged", "false")
+	},
+	"sea_pickle": func(p props) BlockState {
+		return state("sea_pickle", "pickles", strconv.Itoa(p.int("cluster_count")+1), "waterlogged", strconv.FormatBool(p.int("dead_bit") == 0))
+	},
+	"turtle_egg": func(p props) BlockState {
+		eggs := map[string]string{"one_egg": "1", "two_egg": "2", "three_egg": "3", "four_egg": "4"}[p.str("turtle_egg_count")]
+		hatch := map[string]string{"no_cracks": "0", "cracked": "1", "max_cracked": "2"}[p.str("cracked_state")]
+		return state("turtle_egg", "eggs", eggs, "hatch", hatch)
+	},
+	"lantern": func(p props) BlockState {
+		return state("lantern", "hanging", p.bool("hanging"), "waterlogged", "false")
+	},
+	"soul_lantern": func(p props) BlockState {
+		return state("soul_lantern", "hanging", p.bool("hanging"), "waterlogged", "false")
+	},
+	"pointed_dripstone": func(p props) BlockState {
+		thickness := p.str("dripstone_thickness")
+		if thickness == "merge" {
+			thickness = "tip_merge"
+		}
+		vertical := "up"
+		if p.int("hanging") != 0 {
+			vertical = "down"
+		}
+		return state("pointed_dripstone", "thickness", thickness, "vertical_direction", vertical, "waterlogged", "false")
+	},
+	"respawn_anchor": func(p props) BlockState {
+		return state("respawn_anchor", "charges", strconv.Itoa(p.int("respawn_anchor_charge")))
+	},
+	"tnt": func(p props) BlockState {
+		return state("tnt", "unstable", p.bool("explode_bit"))
+	},
+	"structure_block": func(p props) BlockState {
+		mode := p.str("structure_block_type")
+		if mode != "save" && mode != "load" && mode != "corner" {
+			mode = "data"
+		}
+		return state("structure_block", "mode", mode)
+	},
+	"daylight_detector": func(p props) BlockState {
+		return state("daylight_detector", "inverted", "false", "power", strconv.Itoa(p.int("redstone_signal")))
+	},
+	"daylight_detector_inverted": func(p props) BlockState {
+		return state("daylight_detector", "inverted", "true", "power", strconv.Itoa(p.int("redstone_signal")))
+	},
+	"redstone_wire": func(p props) BlockState {
+		return state("redstone_wire", "power", strconv.Itoa(p.int("redstone_signal")))
+	},
+	"light_weighted_pressure_plate": func(p props) BlockState {
+		return state("light_weighted_pressure_plate", "power", strconv.Itoa(p.int("redstone_signal")))
+	},
+	"heavy_weighted_pressure_plate": func(p props) BlockState {
+		return state("heavy_weighted_pressure_plate", "power", strconv.Itoa(p.int("redstone_signal")))
+	},
+	"redstone_lamp":              fixed("redstone_lamp", "lit", "false"),
+	"lit_redstone_lamp":          fixed("redstone_lamp", "lit", "true"),
+	"redstone_ore":               fixed("redstone_ore", "lit", "false"),
+	"lit_redstone_ore":           fixed("redstone_ore", "lit", "true"),
+	"deepslate_redstone_ore":     fixed("deepslate_redstone_ore", "lit", "false"),
+	"lit_deepslate_redstone_ore": fixed("deepslate_redstone_ore", "lit", "true"),
+	"vine": func(p props) BlockState {
+		return state("vine", append(faceBits(p, "vine_direction_bits", "south", "west", "north", "east"), "up", "false")...)
+	},
+	"glow_lichen": func(p props) BlockState {
+		return state("glow_lichen", append(faceBits(p, "multi_face_direction_bits", "down", "up", "south", "west", "north", "east"), "waterlogged", "false")...)
+	},
+	"sculk_vein": func(p props) BlockState {
+		return state("sculk_vein", append(faceBits(p, "multi_face_direction_bits", "down", "up", "south", "west", "north", "east"), "waterlogged", "false")...)
+	},
+	"brown_mushroom_block": mushroomBlock("brown_mushroom_block"),
+	"red_mushroom_block":   mushroomBlock("red_mushroom_block"),
+	"sculk_catalyst": func(p props) BlockState {
+		return state("sculk_catalyst", "bloom", p.bool("bloom"))
+	},
+	"sculk_shrieker": func(p props) BlockState {
+		return state("sculk_shrieker", "shrieking", p.bool("active"), "can_summon", p.bool("can_summon"), "waterlogged", "false")
+	},
+	"sculk_sensor": func(p props) BlockState {
+		phase := "inactive"
+		if p.int("powered_bit") != 0 {
+			phase = "active"
+		}
+		return state("sculk_sensor", "sculk_sensor_phase", phase, "power", "0", "waterlogged", "false")
+	},
+	"portal": func(p props) BlockState {
+		a := p.str("portal_axis")
+		if a != "z" {
+			a = "x"
+		}
+		return state("nether_portal", "axis", a)
+	},
+	"brewing_stand": func(p props) BlockState {
+		return state("brewing_stand", "has_bottle_0", p.bool("brewing_stand_slot_a_bit"), "has_bottle_1", p.bool("brewing_stand_slot_b_bit"), "has_bottle_2", p.bool("brewing_stand_slot_c_bit"))
+	},
+	"light_block": func(p props) BlockState {
+		return state("light", "level", strconv.Itoa(p.int("block_light_level")), "waterlogged", "false")
+	},
+	"standing_banner": func(p props) BlockState {
+		return state("white_banner", "rotation", strconv.Itoa(p.int("ground_sign_direction")))
+	},
+	"candle": func(p props) BlockState {
+		return candle("candle", p)
+	},
+	"candle_cake": func(p props) BlockState {
+		return state("candle_cake", "lit", p.bool("lit"))
+	},
+	"standing_sign": func(p props) BlockState {
+		return state("oak_sign", "rotation", strconv.Itoa(p.int("ground_sign_direction")), "waterlogged", "false")
+	},
+	"wall_sign":             facingHorizontal("oak_wall_sign", "waterlogged", "false"),
+	"wooden_door":           door("oak_door"),
+	"trapdoor":              trapdoor("oak_trapdoor"),
+	"fence_gate":            fenceGate("oak_fence_gate"),
+	"wooden_button":         button("oak_button"),
+	"wooden_pressure_plate": pressurePlate("oak_pressure_plate"),
+}
+
+func init() {
+	for _, name := range identicalBlocks {
+		blocks[name] = fixed(name)
+	}
+	for _, name := range pillarBlocks {
+		blocks[name] = pillar(name)
+	}
+	for bedrock, java := range renamedBlocks {
+		blocks[bedrock] = fixed(java)
+	}
+	for bedrock, java := range colouredBlocks {
+		blocks[bedrock] = pattern("color", java, colour)
+	}
+	// Podzol and mycelium are snowy in Java Edition if snow is above them, which is not stored in Bedrock Edition.
+	blocks["podzol"] = fixed("podzol", "snowy", "false")
+	blocks["mycelium"] = fixed("mycelium", "snowy", "false")
+}
+
+// family is a group of Bedrock blocks with names ending in the same suffix, such as stairs, which are converted in the
+// same way.
+type family struct {
+	suffix  string
+	convert func(base string, p props) BlockState
+}
+
+// stairNames maps the names of Bedrock stairs without the _stairs suffix that differ from Java Edition to their Java
+// names.
+var stairNames = map[string]string{
+	"stone":             "cobblestone",
+	"normal_stone":      "stone",
+	"end_brick":         "end_stone_brick",
+	"prismarine_bricks": "prismarine_brick",
+}
+
+// families holds the families of Bedrock blocks that are converted by the suffix of their name. Families are checked
+// in order, so families with a suffix ending in the suffix of another family must come first.
+var families = []family{
+	{suffix: "_stairs", convert: func(base string, p props) BlockState {
+		if name, ok := stairNames[base]; ok {
+			base = name
+		}
+		return stairs(base+"_stairs", p)
+	}},
+	{suffix: "_slab", convert: func(base string, p props) BlockState {
+		// Double slabs are named either double_<type>_slab or <type>_double_slab.
+		double := strings.Contains(base, "double_") || strings.HasSuffix(base, "_double")
+		base = strings.TrimSuffix(strings.Replace(base, "double_", "", 1), "_double")
+		return state(base+"_slab", "type", slabType(p, double), "waterlogged", "false")
+	}},
+	{suffix: "_trapdoor", convert: func(base string, p props) BlockState {
+		return trapdoor(base + "_trapdoor")(p)
+	}},
+	{suffix: "_door", convert: func(base string, p props) BlockState {
+		return door(base + "_door")(p)
+	}},
+	{suffix: "_fence_gate", convert: func(base string, p props) BlockState {
+		return fenceGate(base + "_fence_gate")(p)
+	}},
+	{suffix: "_button", convert: func(base string, p props) BlockState {
+		return button(base + "_button")(p)
+	}},
+	{suffix: "_pressure_plate", convert: func(base string, p props) BlockState {
+		return pressurePlate(base + "_pressure_plate")(p)
+	}},
+	{suffix: "_wall_sign", convert: func(base string, p props) BlockState {
+		return facingHorizontal(woodName(base)+"_wall_sign", "waterlogged", "false")(p)
+	}},
+	{suffix: "_standing_sign", convert: func(base string, p props) BlockState {
+		return state(woodName(base)+"_sign", "rotation", strconv.Itoa(p.int("ground_sign_direction")), "waterlogged", "false")
+	}},
+	{suffix: "_wall", convert: wall},
+	{suffix: "_glazed_terracotta", convert: func(base string, p props) BlockState {
+		if base == "silver" {
+			base = "light_gray"
+		}
+		return facingHorizontal(base + "_glazed_terracotta")(p)
+	}},
+	{suffix: "_candle_cake", convert: func(base string, p props) BlockState {
+		return state(base+"_candle_cake", "lit", p.bool("lit"))
+	}},
+	{suffix: "_candle", convert: func(base string, p props) BlockState {
+		return candle(base+"_candle", p)
+	}},
+}
+
+// woodName returns the Java name of a wood type, which is only different for dark oak in the names of signs.
+func woodName(base string) string {
+	if base == "darkoak" {
+		return "dark_oak"
+	}
+	return base
+}
+
+// stairs converts a Bedrock stairs block to the Java stairs with the name passed.
+func stairs(name string, p props) BlockState {
+	h := "bottom"
+	if p.int("upside_down_bit") != 0 {
+		h = "top"
+	}
+	return state(name, "facing", index(weirdo, p.int("weirdo_direction")), "half", h, "shape", "straight", "waterlogged", "false")
+}
+
+// door returns a converter for the doors with the Java name passed.
+func door(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		hinge := "left"
+		if p.int("door_hinge_bit") != 0 {
+			hinge = "right"
+		}
+		return state(name, "facing", index(doorDirections, p.int("direction")), "half", half(p), "hinge", hinge, "open", p.bool("open_bit"), "powered", "false")
+	}
+}
+
+// trapdoor returns a converter for the trapdoors with the Java name passed.
+func trapdoor(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		h := "bottom"
+		if p.int("upside_down_bit") != 0 {
+			h = "top"
+		}
+		return state(name, "facing", index(weirdo, p.int("direction")), "half", h, "open", p.bool("open_bit"), "powered", "false", "waterlogged", "false")
+	}
+}
+
+// fenceGate returns a converter for the fence gates with the Java name passed.
+func fenceGate(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, "facing", index(horizontal, p.int("direction")), "in_wall", p.bool("in_wall_bit"), "open", p.bool("open_bit"), "powered", "false")
+	}
+}
+
+// button returns a converter for the buttons with the Java name passed.
+func button(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		face := "wall"
+		switch p.int("facing_direction") {
+		case 0:
+			face = "ceiling"
+		case 1:
+			face = "floor"
+		}
+		return state(name, "face", face, "facing", horizontalFace(p), "powered", p.bool("button_pressed_bit"))
+	}
+}
+
+// pressurePlate returns a converter for the pressure plates with the Java name passed, other than the weighted ones.
+func pressurePlate(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, "powered", strconv.FormatBool(p.int("redstone_signal") > 0))
+	}
+}
+
+// wall converts a Bedrock wall to the Java wall with the name passed, without the _wall suffix.
+func wall(base string, p props) BlockState {
+	s := state(base+"_wall", "up", p.bool("wall_post_bit"), "waterlogged", "false")
+	for _, side := range []string{"north", "east", "south", "west"} {
+		s.Properties[side] = wallHeights[p.str("wall_connection_type_"+side)]
+		if s.Properties[side] == "" {
+			s.Properties[side] = "none"
+		}
+	}
+	return s
+}
+
+// candle converts a Bedrock candle to the Java candle with the name passed.
+func candle(name string, p props) BlockState {
+	return state(name, "candles", strconv.Itoa(p.int("candles")+1), "lit", p.bool("lit"), "waterlogged", "false")
+}
+
+// half returns the Java half of a block taking up two blocks, from the upper_block_bit property.
+func half(p props) string {
+	if p.int("upper_block_bit") != 0 {
+		return "upper"
+	}
+	return "lower"
+}
+
+// liquid returns a converter for the liquid with the Java name passed.
+func liquid(name string) func(p props) BlockState {
+	return func(p props) BlockState {
+		return state(name, "level", strconv.Itoa(p.int("liquid_depth")))
+	}
+}
+
+// comparatorMode returns the mode property of a Java comparator from the output_subtract_bit property.
+func comparatorMode(p props) []string {
+	if p.int("output_subtract_bit") != 0 {
+		return []string{"mode", "subtract"}
+	}
+	return []string{"mode", "compare"}
+}
+
+// skull converts a Bedrock skull, which only holds the face it is attached to. The type of skull is held in its block
+// entity and defaults to a skeleton skull.
+func skull(p props) BlockState {
+	if f := p.int("facing_direction"); f >= 2 && f <= 5 {
+		return state("skeleton_wall_skull", "facing", faces[f])
+	}
+	return state("skeleton_skull", "rotation", "0")
+}
+
+// coralWallFan returns a converter for the Bedrock hanging coral fans, which hold one of two coral types depending on
+// the coral_hang_type_bit property.
+func coralWallFan(first, second string) func(p props) BlockState {
+	return func(p props) BlockState {
+		t := first
+		if p.int("coral_hang_type_bit") != 0 {
+			t = second
+		}
+		if p.int("dead_bit") != 0 {
+			t = "dead_" + t
+		}
+		f := index([]string{"west", "east", "north", "south"}, p.int("coral_direction"))
+		return state(t+"_coral_wall_fan", "facing", f, "waterlogged", "false")
+	}
+}
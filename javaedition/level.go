@@ -0,0 +1,45 @@
+package javaedition
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"io"
+)
+
+// Level holds the settings of a Java world, stored in its level.dat file, that are kept when converting it.
+type Level struct {
+	// Name is the name of the world.
+	Name string
+	// Spawn is the world spawn.
+	Spawn cube.Pos
+}
+
+// ReadLevel reads the Level from the gzip compressed level.dat file data read from the io.Reader passed.
+func ReadLevel(r io.Reader) (Level, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Level{}, fmt.Errorf("error decompressing level.dat: %w", err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return Level{}, fmt.Errorf("error decompressing level.dat: %w", err)
+	}
+	if err := longArraysAsByteArrays(data); err != nil {
+		return Level{}, fmt.Errorf("error decoding level.dat: %w", err)
+	}
+	var m map[string]interface{}
+	if err := nbt.UnmarshalEncoding(data, &m, nbt.BigEndian); err != nil {
+		return Level{}, fmt.Errorf("error decoding level.dat: %w", err)
+	}
+	d, ok := m["Data"].(map[string]interface{})
+	if !ok {
+		return Level{}, fmt.Errorf("level.dat has no Data compound")
+	}
+	name, _ := d["LevelName"].(string)
+	x, _ := d["SpawnX"].(int32)
+	y, _ := d["SpawnY"].(int32)
+	z, _ := d["SpawnZ"].(int32)
+	return Level{Name: name, Spawn: cube.Pos{int(x), int(y), int(z)}}, nil
+}
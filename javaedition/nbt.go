@@ -0,0 +1,114 @@
+package javaedition
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// NBT tag types as used in the binary NBT format.
+const (
+	tagEnd byte = iota
+	tagByte
+	tagShort
+	tagInt
+	tagLong
+	tagFloat
+	tagDouble
+	tagByteArray
+	tagString
+	tagList
+	tagCompound
+	tagIntArray
+	tagLongArray
+)
+
+// longArraysAsByteArrays rewrites every TAG_Long_Array in the big endian NBT data passed to a TAG_Byte_Array holding
+// the same bytes, which takes up the same amount of space. The NBT decoder does not correctly decode big endian long
+// arrays, so the longs are instead read from the byte arrays.
+func longArraysAsByteArrays(data []byte) error {
+	if len(data) < 3 || data[0] != tagCompound {
+		return fmt.Errorf("NBT data does not start with a compound")
+	}
+	off := 3 + int(binary.BigEndian.Uint16(data[1:]))
+	_, err := rewritePayload(data, off, tagCompound, 0)
+	return err
+}
+
+// rewritePayload walks the payload of the tag of the type passed at the offset passed, rewriting long arrays, and
+// returns the offset right after it. typeOff is the offset of the type of the tag, which is changed if the tag is a
+// long array.
+func rewritePayload(data []byte, off int, tag byte, typeOff int) (int, error) {
+	need := func(n int) error {
+		if n < 0 || off+n > len(data) {
+			return fmt.Errorf("unexpected end of NBT data at offset %v", off)
+		}
+		return nil
+	}
+	switch tag {
+	case tagByte:
+		return off + 1, need(1)
+	case tagShort:
+		return off + 2, need(2)
+	case tagInt, tagFloat:
+		return off + 4, need(4)
+	case tagLong, tagDouble:
+		return off + 8, need(8)
+	case tagString:
+		if err := need(2); err != nil {
+			return 0, err
+		}
+		n := int(binary.BigEndian.Uint16(data[off:]))
+		off += 2
+		return off + n, need(n)
+	case tagByteArray, tagIntArray, tagLongArray:
+		if err := need(4); err != nil {
+			return 0, err
+		}
+		n := int(int32(binary.BigEndian.Uint32(data[off:])))
+		size := 1
+		switch tag {
+		case tagIntArray:
+			size = 4
+		case tagLongArray:
+			size = 8
+			data[typeOff] = tagByteArray
+			binary.BigEndian.PutUint32(data[off:], uint32(n*8))
+		}
+		off += 4
+		return off + n*size, need(n * size)
+	case tagList:
+		if err := need(5); err != nil {
+			return 0, err
+		}
+		elemTypeOff, n := off, int(int32(binary.BigEndian.Uint32(data[off+1:])))
+		elemType := data[off]
+		off += 5
+		for i := 0; i < n; i++ {
+			var err error
+			if off, err = rewritePayload(data, off, elemType, elemTypeOff); err != nil {
+				return 0, err
+			}
+		}
+		return off, nil
+	case tagCompound:
+		for {
+			if err := need(1); err != nil {
+				return 0, err
+			}
+			childTypeOff, childType := off, data[off]
+			off++
+			if childType == tagEnd {
+				return off, nil
+			}
+			if err := need(2); err != nil {
+				return 0, err
+			}
+			off += 2 + int(binary.BigEndian.Uint16(data[off:]))
+			var err error
+			if off, err = rewritePayload(data, off, childType, childTypeOff); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return 0, fmt.Errorf("unknown NBT tag type %v at offset %v", tag, off)
+}
@@ -0,0 +1,191 @@
+package javaedition
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"io"
+	"time"
+)
+
+const (
+	// sectorSize is the size of the sectors that a region file is made up of.
+	sectorSize = 4096
+	// compressionGzip is the compression type of chunks compressed with gzip, which Java Edition no longer writes.
+	compressionGzip = 1
+	// compressionZlib is the compression type of chunks compressed with zlib.
+	compressionZlib = 2
+	// compressionNone is the compression type of chunks that are not compressed.
+	compressionNone = 3
+)
+
+// Region is a region file of Java Edition (.mca), which holds the chunks in an area of 32x32 chunks. Chunks are
+// stored compressed, as they are in the file.
+type Region struct {
+	chunks      [1024][]byte
+	compression [1024]byte
+	timestamps  [1024]uint32
+}
+
+// ReadRegion reads a Region from the region file data passed. Chunks stored outside the region file, which Java
+// Edition does for chunks too large for it, result in an error.
+func ReadRegion(data []byte) (*Region, error) {
+	if len(data) == 0 {
+		// Java Edition sometimes leaves behind empty region files, which hold no chunks.
+		return &Region{}, nil
+	}
+	if len(data) < sectorSize*2 {
+		return nil, fmt.Errorf("region file of %v bytes has no complete header", len(data))
+	}
+	r := &Region{}
+	for i := range r.chunks {
+		loc := binary.BigEndian.Uint32(data[i*4:])
+		if loc == 0 {
+			continue
+		}
+		offset, sectors := int(loc>>8)*sectorSize, int(loc&0xff)
+		if offset < sectorSize*2 || offset+5 > len(data) || sectors == 0 {
+			return nil, fmt.Errorf("chunk %v has invalid location %v in region file", i, loc)
+		}
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		if length < 1 || offset+4+length > len(data) {
+			return nil, fmt.Errorf("chunk %v has invalid length %v in region file", i, length)
+		}
+		compression := data[offset+4]
+		if compression&0x80 != 0 {
+			return nil, fmt.Errorf("chunk %v is stored outside the region file, which is not supported", i)
+		}
+		r.chunks[i], r.compression[i] = data[offset+5:offset+4+length], compression
+		r.timestamps[i] = binary.BigEndian.Uint32(data[sectorSize+i*4:])
+	}
+	return r, nil
+}
+
+// Chunk returns the uncompressed NBT data of the chunk at the position passed in the Region. Only the position
+// within the region is used. False is returned if the Region holds no chunk at the position.
+func (r *Region) Chunk(pos world.ChunkPos) ([]byte, bool, error) {
+	i := regionIndex(pos)
+	c := r.chunks[i]
+	if c == nil {
+		return nil, false, nil
+	}
+	var (
+		reader io.Reader
+		err    error
+	)
+	switch r.compression[i] {
+	case compressionGzip:
+		reader, err = gzip.NewReader(bytes.NewReader(c))
+	case compressionZlib:
+		reader, err = zlib.NewReader(bytes.NewReader(c))
+	case compressionNone:
+		return c, true, nil
+	default:
+		return nil, false, fmt.Errorf("chunk %v has unknown compression type %v", pos, r.compression[i])
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error decompressing chunk %v: %w", pos, err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false, fmt.Errorf("error decompressing chunk %v: %w", pos, err)
+	}
+	return data, true, nil
+}
+
+// Positions returns the positions of all chunks stored in the Region, which is at the region position passed.
+func (r *Region) Positions(regionPos [2]int32) []world.ChunkPos {
+	var positions []world.ChunkPos
+	for i, c := range r.chunks {
+		if c != nil {
+			positions = append(positions, world.ChunkPos{regionPos[0]<<5 + int32(i&31), regionPos[1]<<5 + int32(i>>5)})
+		}
+	}
+	return positions
+}
+
+// RegionPos returns the position of the region that holds the chunk at the position passed.
+func RegionPos(pos world.ChunkPos) [2]int32 {
+	return [2]int32{pos[0] >> 5, pos[1] >> 5}
+}
+
+// RegionFileName returns the name of the file of the region at the position passed, such as r.-1.0.mca.
+func RegionFileName(pos [2]int32) string {
+	return fmt.Sprintf("r.%v.%v.mca", pos[0], pos[1])
+}
+
+// SetChunk compresses the uncompressed NBT data of a chunk, as returned by Converter.EncodeChunk, and stores it in
+// the Region at the position passed. Only the position within the region is used.
+func (r *Region) SetChunk(pos world.ChunkPos, data []byte) error {
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)/4))
+	w := zlib.NewWriter(buf)
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error compressing chunk %v: %w", pos, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error compressing chunk %v: %w", pos, err)
+	}
+	if sectors := (buf.Len() + 5 + sectorSize - 1) / sectorSize; sectors > 0xff {
+		return fmt.Errorf("chunk %v is too large for a region file: %v bytes compressed", pos, buf.Len())
+	}
+	i := regionIndex(pos)
+	r.chunks[i], r.compression[i], r.timestamps[i] = buf.Bytes(), compressionZlib, uint32(time.Now().Unix())
+	return nil
+}
+
+// Len returns the amount of chunks stored in the Region.
+func (r *Region) Len() (n int) {
+	for _, c := range r.chunks {
+		if c != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTo writes the Region to the io.Writer passed in the region file format. The file starts with a table of the
+// locations of the chunks and a table of the times they were last modified, after which every chunk is written
+// aligned to a sector with its length and compression type in front of it.
+func (r *Region) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, sectorSize*2)
+	offset := 2
+	for i, c := range r.chunks {
+		if c == nil {
+			continue
+		}
+		sectors := (len(c) + 5 + sectorSize - 1) / sectorSize
+		binary.BigEndian.PutUint32(header[i*4:], uint32(offset)<<8|uint32(sectors))
+		binary.BigEndian.PutUint32(header[sectorSize+i*4:], r.timestamps[i])
+		offset += sectors
+	}
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, fmt.Errorf("error writing region header: %w", err)
+	}
+	for i, c := range r.chunks {
+		if c == nil {
+			continue
+		}
+		sectors := (len(c) + 5 + sectorSize - 1) / sectorSize
+		data := make([]byte, sectors*sectorSize)
+		binary.BigEndian.PutUint32(data, uint32(len(c)+1))
+		data[4] = r.compression[i]
+		copy(data[5:], c)
+
+		n, err := w.Write(data)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("error writing region chunk: %w", err)
+		}
+	}
+	return written, nil
+}
+
+// regionIndex returns the index of the chunk at the position passed in the tables of a region file.
+func regionIndex(pos world.ChunkPos) int {
+	return int(pos[0]&31) + int(pos[1]&31)*32
+}
@@ -0,0 +1,156 @@
+package javaedition
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/structure"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"io"
+	"reflect"
+)
+
+// schematicV2 is the NBT layout of a version 2 Sponge schematic.
+type schematicV2 struct {
+	Version       int32                    `nbt:"Version"`
+	DataVersion   int32                    `nbt:"DataVersion"`
+	Width         int16                    `nbt:"Width"`
+	Height        int16                    `nbt:"Height"`
+	Length        int16                    `nbt:"Length"`
+	Offset        [3]int32                 `nbt:"Offset"`
+	Metadata      map[string]interface{}   `nbt:"Metadata"`
+	PaletteMax    int32                    `nbt:"PaletteMax"`
+	Palette       map[string]int32         `nbt:"Palette"`
+	BlockData     interface{}              `nbt:"BlockData"`
+	BlockEntities []map[string]interface{} `nbt:"BlockEntities"`
+}
+
+// schematicV3 is the NBT layout of a version 3 Sponge schematic, which is held in a Schematic compound in the root
+// compound of the file.
+type schematicV3 struct {
+	Version     int32          `nbt:"Version"`
+	DataVersion int32          `nbt:"DataVersion"`
+	Width       int16          `nbt:"Width"`
+	Height      int16          `nbt:"Height"`
+	Length      int16          `nbt:"Length"`
+	Offset      [3]int32       `nbt:"Offset"`
+	Blocks      blockContainer `nbt:"Blocks"`
+}
+
+// blockContainer holds the blocks of a version 3 Sponge schematic.
+type blockContainer struct {
+	Palette       map[string]int32         `nbt:"Palette"`
+	Data          interface{}              `nbt:"Data"`
+	BlockEntities []map[string]interface{} `nbt:"BlockEntities"`
+}
+
+// WriteSchematic writes the structure passed to the io.Writer as a Sponge schematic (.schem) of the version passed,
+// which must be 2 or 3. Blocks and block entities are converted to Java Edition, and structure void is written as
+// air. The names of the blocks that could not be converted are returned together with the amount of each of them.
+func WriteSchematic(w io.Writer, s *structure.Structure, version int) (unmapped map[string]int, err error) {
+	if version != 2 && version != 3 {
+		return nil, fmt.Errorf("unsupported schematic version %v, must be 2 or 3", version)
+	}
+	size, origin := s.Size(), s.Origin()
+	if size[0] > 0xffff || size[1] > 0xffff || size[2] > 0xffff {
+		return nil, fmt.Errorf("structure of %vx%vx%v is too large for a schematic", size[0], size[1], size[2])
+	}
+
+	var (
+		conv          = NewConverter()
+		palette       = make(map[string]int32)
+		data          = make([]byte, 0, size[0]*size[1]*size[2])
+		blockEntities = make([]map[string]interface{}, 0)
+		buf           [binary.MaxVarintLen32]byte
+	)
+	// Blocks in a schematic are ordered by Y, then Z and then X.
+	for y := 0; y < size[1]; y++ {
+		for z := 0; z < size[2]; z++ {
+			for x := 0; x < size[0]; x++ {
+				block := air
+				if layer0, ok := s.Block(x, y, z, 0); ok {
+					layer1, _ := s.Block(x, y, z, 1)
+					block = conv.Block(layer0, layer1)
+				}
+				key := block.String()
+				index, ok := palette[key]
+				if !ok {
+					index = int32(len(palette))
+					palette[key] = index
+				}
+				data = append(data, buf[:binary.PutUvarint(buf[:], uint64(index))]...)
+
+				blockEntity, ok := s.BlockEntity(x, y, z)
+				if !ok {
+					continue
+				}
+				id, converted, ok := ConvertBlockEntity(blockEntity, block)
+				if !ok {
+					continue
+				}
+				pos := [3]int32{int32(x), int32(y), int32(z)}
+				if version == 2 {
+					converted["Id"], converted["Pos"] = id, pos
+					blockEntities = append(blockEntities, converted)
+					continue
+				}
+				blockEntities = append(blockEntities, map[string]interface{}{"Id": id, "Pos": pos, "Data": converted})
+			}
+		}
+	}
+
+	offset := [3]int32{int32(origin[0]), int32(origin[1]), int32(origin[2])}
+	var v interface{}
+	if version == 2 {
+		v = schematicV2{
+			Version:     2,
+			DataVersion: DataVersion,
+			Width:       int16(uint16(size[0])),
+			Height:      int16(uint16(size[1])),
+			Length:      int16(uint16(size[2])),
+			Offset:      offset,
+			// The WorldEdit offset is the position of the origin of the clipboard relative to its lowest corner,
+			// which is placed at the lowest corner so that the schematic is pasted in the same way as a structure.
+			Metadata:      map[string]interface{}{"WEOffsetX": int32(0), "WEOffsetY": int32(0), "WEOffsetZ": int32(0)},
+			PaletteMax:    int32(len(palette)),
+			Palette:       palette,
+			BlockData:     byteArray(data),
+			BlockEntities: blockEntities,
+		}
+	} else {
+		v = map[string]interface{}{"Schematic": schematicV3{
+			Version:     3,
+			DataVersion: DataVersion,
+			Width:       int16(uint16(size[0])),
+			Height:      int16(uint16(size[1])),
+			Length:      int16(uint16(size[2])),
+			Offset:      offset,
+			Blocks:      blockContainer{Palette: palette, Data: byteArray(data), BlockEntities: blockEntities},
+		}}
+	}
+	encoded, err := nbt.MarshalEncoding(v, nbt.BigEndian)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding schematic: %w", err)
+	}
+	if version == 2 {
+		// The encoder always writes an empty name for the root compound, but version 2 schematics require it to be
+		// named Schematic. The empty name is made up of the two bytes following the tag type.
+		encoded = append([]byte{encoded[0], 0, 9, 'S', 'c', 'h', 'e', 'm', 'a', 't', 'i', 'c'}, encoded[3:]...)
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(encoded); err != nil {
+		return nil, fmt.Errorf("error writing schematic: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("error writing schematic: %w", err)
+	}
+	return conv.Unmapped(), nil
+}
+
+// byteArray returns the bytes passed as a byte array, which is encoded as a TAG_Byte_Array, unlike a byte slice.
+func byteArray(b []byte) interface{} {
+	arr := reflect.New(reflect.ArrayOf(len(b), reflect.TypeOf(byte(0)))).Elem()
+	reflect.Copy(arr, reflect.ValueOf(b))
+	return arr.Interface()
+}
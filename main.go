@@ -5,38 +5,60 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
 	"github.com/justtaldevelops/worldcompute/dragonfly/mcdb"
 	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/justtaldevelops/worldcompute/structure"
 	"github.com/justtaldevelops/worldcompute/worldrenderer"
+	"github.com/justtaldevelops/worldcompute/worldsearch"
 	"github.com/pelletier/go-toml"
 	"github.com/sandertv/gophertunnel/minecraft"
 	"github.com/sandertv/gophertunnel/minecraft/auth"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 	"github.com/sandertv/gophertunnel/minecraft/protocol/packet"
 	"github.com/sandertv/gophertunnel/minecraft/text"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
-	mu       sync.Mutex
-	chunks   = make(map[world.ChunkPos]*chunk.Chunk)
+	// caches holds the chunks received from the server for every dimension. Chunks are kept when the player changes
+	// dimension, so that the dimensions may be linked in the renderer.
+	caches = map[world.Dimension]*worldrenderer.CacheSource{
+		world.Overworld: worldrenderer.NewCacheSource(),
+		world.Nether:    worldrenderer.NewCacheSource(),
+		world.End:       worldrenderer.NewCacheSource(),
+	}
 	renderer *worldrenderer.Renderer
 )
 
-// main starts the renderer and proxy.
+// main starts the renderer and proxy. If any arguments are passed, the command line command they specify is run
+// instead.
 func main() {
 	log := logrus.New()
 	log.Formatter = &logrus.TextFormatter{ForceColors: true}
 	log.Level = logrus.DebugLevel
 
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	src := tokenSource()
 	conf, err := readConfig()
 	if err != nil {
@@ -68,14 +90,28 @@ func main() {
 		}
 	}()
 
-	renderer = worldrenderer.NewRendererDirect(4, 6.5, mgl64.Vec2{}, &mu, chunks)
+	renderer = worldrenderer.NewRenderer(4, 6.5, mgl64.Vec2{}, world.Overworld, caches[world.Overworld])
+	renderer.SetSource(world.Nether, caches[world.Nether])
+	renderer.SetSource(world.End, caches[world.End])
+	renderer.SetTimeLapseInterval(time.Duration(conf.Renderer.TimeLapseInterval) * time.Second)
+	if conf.Renderer.ResourcePack != "" || conf.Renderer.ColourOverrides != "" {
+		p, err := loadPalette(conf.Renderer.ResourcePack, conf.Renderer.ColourOverrides)
+		if err != nil {
+			log.Fatal(err)
+		}
+		renderer.SetPalette(p)
+	}
+	if err := runRenderer(renderer); err != nil {
+		log.Fatal(err)
+	}
+}
 
+// runRenderer opens the window of the renderer passed and runs it until the window is closed.
+func runRenderer(r *worldrenderer.Renderer) error {
 	ebiten.SetWindowSize(1718, 1360)
 	ebiten.SetWindowResizable(true)
 	ebiten.SetWindowTitle("worldrenderer")
-	if err := ebiten.RunGame(renderer); err != nil {
-		log.Fatal(err)
-	}
+	return ebiten.RunGame(r)
 }
 
 // handleConn handles a new incoming minecraft.Conn from the minecraft.Listener passed.
@@ -99,14 +135,9 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 	oldFormat := data.BaseGameVersion == "1.17.40"
 
 	pos := data.PlayerPosition
-	dimension := world.Dimension(world.Overworld)
-	switch data.Dimension {
-	case 1:
-		dimension = world.Nether
-	case 2:
-		dimension = world.End
-	}
+	dimension := dimensionByID(data.Dimension)
 
+	renderer.SetDimension(dimension)
 	renderer.Recenter(mgl64.Vec2{
 		float64(pos.X()),
 		float64(pos.Z()),
@@ -114,6 +145,12 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 
 	log.Println("completed connection to " + config.Connection.RemoteAddress)
 
+	// selection holds the two corners of the selection exported using /export, and selected if they have been set.
+	var (
+		selection [2]cube.Pos
+		selected  [2]bool
+	)
+
 	var g sync.WaitGroup
 	g.Add(2)
 	go func() {
@@ -148,12 +185,14 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 					float64(pos.X()),
 					float64(pos.Z()),
 				})
+				renderer.UpdateLocalPlayer(vec64(pos), float64(pk.Yaw))
 			case *packet.MovePlayer:
 				pos = pk.Position
 				renderer.Recenter(mgl64.Vec2{
 					float64(pos.X()),
 					float64(pos.Z()),
 				})
+				renderer.UpdateLocalPlayer(vec64(pos), float64(pk.Yaw))
 			case *packet.CommandRequest:
 				line := strings.Split(pk.CommandLine, " ")
 				if len(line) == 0 {
@@ -164,40 +203,253 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 					_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Terminated save.</italic></bold></red>")})
 					continue
 				case "/reset":
-					mu.Lock()
-					for chunkPos := range chunks {
-						delete(chunks, chunkPos)
+					for _, cache := range caches {
+						cache.Clear()
+					}
+					continue
+				case "/isometric":
+					fileName := strings.Join(line[1:], " ")
+					if fileName == "" {
+						fileName = "isometric.png"
+					}
+					_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<aqua><bold><italic>Rendering isometric image...</italic></bold></aqua>")})
+					go func() {
+						f, err := os.Create(fileName)
+						if err != nil {
+							log.Errorf("error creating isometric image: %v", err)
+							return
+						}
+						defer f.Close()
+						if err := renderer.ExportIsometric(f); err != nil {
+							log.Errorf("error rendering isometric image: %v", err)
+							return
+						}
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Saved isometric image to \"%v\"!</italic></bold></green>", fileName)})
+					}()
+					continue
+				case "/pos1", "/pos2":
+					i := 0
+					if line[0] == "/pos2" {
+						i = 1
+					}
+					p, err := parseBlockPos(line[1:], blockPos(pos))
+					if err != nil {
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>%v</italic></bold></red>", err)})
+						continue
+					}
+					selection[i], selected[i] = p, true
+					_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Set position %v to %v, %v, %v.</italic></bold></green>", i+1, p.X(), p.Y(), p.Z())})
+					continue
+				case "/export":
+					if !selected[0] || !selected[1] {
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Select two corners using /pos1 and /pos2 first.</italic></bold></red>")})
+						continue
+					}
+					fileName := strings.Join(line[1:], " ")
+					if fileName == "" {
+						fileName = "structure.mcstructure"
+					}
+					cache, a, b := caches[dimension], selection[0], selection[1]
+					go func() {
+						s := structure.Read(cache, a, b)
+						unmapped, err := writeExport(fileName, s, 2)
+						if err != nil {
+							log.Errorf("error exporting structure: %v", err)
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Error exporting structure: %v</italic></bold></red>", err)})
+							return
+						}
+						size := s.Size()
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Exported %vx%vx%v structure to \"%v\"!</italic></bold></green>", size[0], size[1], size[2], fileName)})
+						if report := unmappedReport(unmapped, "Java Edition"); len(report) > 0 {
+							log.Println(strings.Join(report, "\n"))
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<yellow><bold><italic>%v block types could not be converted to Java Edition, see the log for details.</italic></bold></yellow>", len(unmapped))})
+						}
+					}()
+					continue
+				case "/import":
+					if len(line) < 2 || len(line) > 4 {
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Usage: /import <file> [rotation] [mirror]</italic></bold></red>")})
+						continue
+					}
+					rotate, mirror := 0, ""
+					if len(line) > 2 {
+						var err error
+						if rotate, err = strconv.Atoi(line[2]); err != nil {
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Invalid rotation %q.</italic></bold></red>", line[2])})
+							continue
+						}
+					}
+					if len(line) > 3 {
+						mirror = line[3]
+					}
+					// The structure is placed at the first corner of the selection if set, or at the player otherwise.
+					at := blockPos(pos)
+					if selected[0] {
+						at = selection[0]
 					}
-					mu.Unlock()
+					fileName, cache, r := line[1], caches[dimension], dimension.Range()
+					go func() {
+						s, unknown, err := readStructure(fileName, rotate, mirror)
+						if err != nil {
+							log.Errorf("error importing structure: %v", err)
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Error importing structure: %v</italic></bold></red>", err)})
+							return
+						}
+						size := s.Size()
+						for _, chunkPos := range s.Chunks(at) {
+							cache.LoadOrStore(chunkPos, chunk.New(airRID, r))
+							blockEntities := cache.BlockEntities(chunkPos)
+							cache.Modify(chunkPos, func(c *chunk.Chunk) {
+								blockEntities = s.PlaceChunk(at, chunkPos, c, blockEntities)
+							})
+							cache.SetBlockEntities(chunkPos, blockEntities)
+							// The sub chunks the structure was placed in are treated as received, so that they are kept
+							// when merging the chunks into a saved world.
+							cache.MarkReceived(chunkPos, subChunkMask(r, at.Y(), at.Y()+size[1]-1), false)
+						}
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Imported %vx%vx%v structure at %v, %v, %v!</italic></bold></green>", size[0], size[1], size[2], at.X(), at.Y(), at.Z())})
+						if len(unknown) > 0 {
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<yellow><bold><italic>Skipped unknown blocks: %v</italic></bold></yellow>", strings.Join(unknown, ", "))})
+						}
+					}()
+					continue
+				case "/slime":
+					chunkPos := world.ChunkPos{int32(math.Floor(float64(pos.X()))) >> 4, int32(math.Floor(float64(pos.Z()))) >> 4}
+					if world.SlimeChunk(chunkPos) {
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Chunk %v, %v is a slime chunk.</italic></bold></green>", chunkPos.X(), chunkPos.Z())})
+					} else {
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Chunk %v, %v is not a slime chunk.</italic></bold></red>", chunkPos.X(), chunkPos.Z())})
+					}
+					continue
+				case "/search":
+					query := strings.Join(line[1:], " ")
+					if query == "" {
+						renderer.Highlight(nil)
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Cleared all highlighted blocks.</italic></bold></green>")})
+						continue
+					}
+					q, err := worldsearch.ParseQuery(query)
+					if err != nil {
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>%v</italic></bold></red>", err)})
+						continue
+					}
+					_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<aqua><bold><italic>Searching for %v...</italic></bold></aqua>", q)})
+					cache := caches[dimension]
+					go func() {
+						found := worldsearch.NewSearcher(q).Search(cache)
+						renderer.Highlight(found)
 
-					renderer.Rerender()
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Found %v blocks matching %v.</italic></bold></green>", len(found), q)})
+						for i, p := range found {
+							if i == 10 {
+								_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<grey>...and %v more</grey>", len(found)-i)})
+								break
+							}
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<grey>%v, %v, %v</grey>", p.X(), p.Y(), p.Z())})
+						}
+					}()
 					continue
 				case "/save":
+					// With merge as the first argument, the chunks are merged into the chunks already in the folder
+					// instead of replacing them.
+					merge := len(line) > 2 && line[1] == "merge"
+					if merge {
+						line = line[1:]
+					}
 					saveName := strings.Join(line[1:], " ")
 					_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<aqua><bold><italic>Processing chunks to be saved...</italic></bold></aqua>")})
 					go func() {
-						prov, err := mcdb.New(saveName, dimension)
-						if err != nil {
-							panic(err)
+						// Names ending in .mcworld are saved to a temporary folder first, which is written to the
+						// archive once all providers are closed.
+						folder := saveName
+						archive := strings.EqualFold(filepath.Ext(saveName), mcdb.ArchiveExtension)
+						if archive {
+							var err error
+							if folder, err = os.MkdirTemp("", "worldcompute-save-"); err != nil {
+								_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Error creating temporary folder: %v</italic></bold></red>", err)})
+								return
+							}
+							defer os.RemoveAll(folder)
+							if _, err := os.Stat(saveName); merge && err == nil {
+								if err := mcdb.ExtractArchive(saveName, folder); err != nil {
+									_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Error extracting archive to merge into: %v</italic></bold></red>", err)})
+									return
+								}
+							}
+						}
+						// The dimensions are saved in parallel. Their providers share the same database, which is only
+						// closed, and the level.dat only written, once the last of them is closed. Errors are collected
+						// per dimension, so that a dimension failing to save does not stop the others.
+						var wg sync.WaitGroup
+						dims := []world.Dimension{world.Overworld, world.Nether, world.End}
+						errs := make([]error, len(dims))
+						for i, dim := range dims {
+							cache := caches[dim]
+							positions := cache.Positions()
+							if len(positions) == 0 && dim != world.Overworld {
+								continue
+							}
+							wg.Add(1)
+							go func(i int, dim world.Dimension) {
+								defer wg.Done()
+								prov, err := mcdb.New(folder, dim)
+								if err != nil {
+									errs[i] = fmt.Errorf("error opening world: %w", err)
+									return
+								}
+								for _, pos := range positions {
+									if err := saveChunk(prov, cache, pos, merge); err != nil {
+										errs[i] = err
+										break
+									}
+								}
+								if errs[i] == nil {
+									if dim == world.Overworld {
+										for _, pack := range serverConn.ResourcePacks() {
+											if pack.Encrypted() {
+												log.Warnf("skipping encrypted pack %v", pack.Name())
+												continue
+											}
+											if err := prov.SavePack(pack); err != nil {
+												log.Errorf("error saving pack: %v", err)
+											}
+										}
+									}
+									prov.SaveSettings(&world.Settings{
+										Name:        data.WorldName,
+										Spawn:       [3]int{int(pos.X()), int(pos.Y()), int(pos.Z())},
+										Time:        data.Time,
+										NetherScale: 8,
+									})
+								}
+								if err := prov.Close(); err != nil && errs[i] == nil {
+									errs[i] = fmt.Errorf("error closing world: %w", err)
+								}
+							}(i, dim)
 						}
-						for pos, c := range chunks {
-							c.Compact()
-							err = prov.SaveChunk(pos, c)
+						wg.Wait()
+						failed := false
+						for i, err := range errs {
 							if err != nil {
-								panic(err)
+								failed = true
+								_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Error saving %v: %v</italic></bold></red>", dims[i], err)})
 							}
 						}
-						prov.SaveSettings(&world.Settings{
-							Name:  data.WorldName,
-							Spawn: [3]int{int(pos.X()), int(pos.Y()), int(pos.Z())},
-							Time:  data.Time,
-						})
-						err = prov.Close()
-						if err != nil {
-							panic(err)
+						if failed {
+							return
+						}
+						if archive {
+							if err := mcdb.WriteArchive(folder, saveName); err != nil {
+								_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<red><bold><italic>Error writing archive: %v</italic></bold></red>", err)})
+								return
+							}
 						}
 
-						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Saved all chunks received to the \"%v\" folder!</italic></bold></green>", saveName)})
+						if merge {
+							_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Merged all chunks received into \"%v\"!</italic></bold></green>", saveName)})
+							return
+						}
+						_ = conn.WritePacket(&packet.Text{Message: text.Colourf("<green><bold><italic>Saved all chunks received to \"%v\"!</italic></bold></green>", saveName)})
 					}()
 					continue
 				}
@@ -230,7 +482,42 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 				})
 				pk.Commands = append(pk.Commands, protocol.Command{
 					Name:        "save",
-					Description: text.Colourf("<dark-aqua>Save all downloaded chunks to a folder</dark-aqua>"),
+					Description: text.Colourf("<dark-aqua>Save all downloaded chunks to a folder or .mcworld file, or merge them into it with merge</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "isometric",
+					Description: text.Colourf("<dark-aqua>Render all downloaded chunks isometrically to an image</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "slime",
+					Description: text.Colourf("<dark-aqua>Check if you are standing in a slime chunk</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "search",
+					Description: text.Colourf("<dark-aqua>Search all downloaded chunks for a block and highlight the results</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "pos1",
+					Description: text.Colourf("<dark-aqua>Set the first corner of the selection</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "pos2",
+					Description: text.Colourf("<dark-aqua>Set the second corner of the selection</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "export",
+					Description: text.Colourf("<dark-aqua>Export the selection to a .mcstructure or .schem file</dark-aqua>"),
+					Flags:       0x1,
+				})
+				pk.Commands = append(pk.Commands, protocol.Command{
+					Name:        "import",
+					Description: text.Colourf("<dark-aqua>Import a .mcstructure file into the downloaded chunks</dark-aqua>"),
 					Flags:       0x1,
 				})
 				pk.Commands = append(pk.Commands, protocol.Command{
@@ -245,8 +532,46 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 						float64(pos.X()),
 						float64(pos.Z()),
 					})
+					renderer.UpdateLocalPlayer(vec64(pos), float64(pk.Yaw))
+				} else {
+					renderer.MoveEntity(pk.EntityRuntimeID, vec64(pk.Position), float64(pk.Yaw))
 				}
+			case *packet.AddPlayer:
+				renderer.AddPlayer(pk.EntityRuntimeID, pk.EntityUniqueID, pk.Username, vec64(pk.Position), float64(pk.Yaw))
+			case *packet.AddActor:
+				renderer.AddEntity(pk.EntityRuntimeID, pk.EntityUniqueID, pk.EntityType, vec64(pk.Position), float64(pk.Yaw))
+				caches[dimension].AddEntity(pk.EntityRuntimeID, map[string]interface{}{
+					"identifier": pk.EntityType,
+					"UniqueID":   pk.EntityUniqueID,
+					"Pos":        []float32{pk.Position.X(), pk.Position.Y(), pk.Position.Z()},
+					"Rotation":   []float32{pk.Yaw, pk.Pitch},
+					"Motion":     []float32{pk.Velocity.X(), pk.Velocity.Y(), pk.Velocity.Z()},
+				})
+			case *packet.MoveActorAbsolute:
+				renderer.MoveEntity(pk.EntityRuntimeID, vec64(pk.Position), float64(pk.Rotation.Z()))
+				caches[dimension].MoveEntity(pk.EntityRuntimeID, vec64(pk.Position))
+			case *packet.MoveActorDelta:
+				if entityPos, yaw, ok := renderer.EntityPosition(pk.EntityRuntimeID); ok {
+					if pk.Flags&packet.MoveActorDeltaFlagHasX != 0 {
+						entityPos[0] = float64(pk.Position.X())
+					}
+					if pk.Flags&packet.MoveActorDeltaFlagHasY != 0 {
+						entityPos[1] = float64(pk.Position.Y())
+					}
+					if pk.Flags&packet.MoveActorDeltaFlagHasZ != 0 {
+						entityPos[2] = float64(pk.Position.Z())
+					}
+					if pk.Flags&packet.MoveActorDeltaFlagHasRotZ != 0 {
+						yaw = float64(pk.Rotation.Z())
+					}
+					renderer.MoveEntity(pk.EntityRuntimeID, entityPos, yaw)
+					caches[dimension].MoveEntity(pk.EntityRuntimeID, entityPos)
+				}
+			case *packet.RemoveActor:
+				renderer.RemoveEntity(pk.EntityUniqueID)
+				caches[dimension].RemoveEntity(pk.EntityUniqueID)
 			case *packet.SubChunk:
+				cache, r := caches[dimension], dimension.Range()
 				go func() {
 					for _, entry := range pk.SubChunkEntries {
 						if entry.Result == protocol.SubChunkResultSuccess {
@@ -255,55 +580,84 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 								pk.Position.Z() + int32(entry.Offset[2]),
 							}
 
-							mu.Lock()
-							c, ok := chunks[offsetPos]
-							if !ok {
-								c = chunk.New(airRID, dimension.Range())
-								chunks[offsetPos] = c
-							}
-							mu.Unlock()
-
-							var ind byte
-							newSub, err := chunk.DecodeSubChunk(bytes.NewBuffer(entry.RawPayload), c, &ind, chunk.NetworkEncoding)
-							if err == nil {
-								mu.Lock()
-								c.Sub()[ind] = newSub
-								mu.Unlock()
+							cache.LoadOrStore(offsetPos, chunk.New(airRID, r))
+							cache.Modify(offsetPos, func(c *chunk.Chunk) {
+								var ind byte
+								buf := bytes.NewBuffer(entry.RawPayload)
+								newSub, err := chunk.DecodeSubChunk(buf, c, &ind, chunk.NetworkEncoding)
+								if err == nil {
+									c.Sub()[ind] = newSub
+									cache.MarkReceived(offsetPos, 1<<ind, false)
+								}
+								// The block entities of the sub chunk follow the sub chunk itself.
+								dec := nbt.NewDecoderWithEncoding(buf, nbt.NetworkLittleEndian)
+								for err == nil && buf.Len() != 0 {
+									var blockEntity map[string]interface{}
+									if err = dec.Decode(&blockEntity); err == nil {
+										if x, y, z, ok := nbtPos(blockEntity); ok {
+											cache.SetBlockEntity(cube.Pos{x, y, z}, blockEntity)
+										}
+									}
+								}
+							})
+						} else if entry.Result == protocol.SubChunkResultSuccessAllAir {
+							offsetPos := world.ChunkPos{
+								pk.Position.X() + int32(entry.Offset[0]),
+								pk.Position.Z() + int32(entry.Offset[2]),
 							}
-
-							renderer.RerenderChunk(offsetPos)
+							index := int(pk.Position.Y()) + int(entry.Offset[1]) - (r.Min() >> 4)
+							cache.LoadOrStore(offsetPos, chunk.New(airRID, r))
+							cache.Modify(offsetPos, func(c *chunk.Chunk) {
+								if index >= 0 && index < len(c.Sub()) {
+									c.Sub()[index] = chunk.NewSubChunk(airRID)
+									cache.MarkReceived(offsetPos, 1<<index, false)
+								}
+							})
 						}
 					}
 				}()
+			case *packet.BlockActorData:
+				cache := caches[dimension]
+				cache.SetBlockEntity(cube.Pos{int(pk.Position.X()), int(pk.Position.Y()), int(pk.Position.Z())}, pk.NBTData)
 			case *packet.ChangeDimension:
-				mu.Lock()
-				for chunkPos := range chunks {
-					delete(chunks, chunkPos)
-				}
-				mu.Unlock()
-
-				dimension = world.Dimension(world.Overworld)
-				switch pk.Dimension {
-				case 1:
-					dimension = world.Nether
-				case 2:
-					dimension = world.End
-				}
+				// The server does not remove the entities of the dimension left, so they would otherwise stay forever.
+				caches[dimension].ClearEntities()
+				dimension = dimensionByID(pk.Dimension)
 
-				renderer.Rerender()
+				renderer.SetDimension(dimension)
+				renderer.ClearEntities()
 			case *packet.LevelChunk:
 				switch pk.SubChunkRequestMode {
 				case protocol.SubChunkRequestModeLegacy:
+					cache, r := caches[dimension], dimension.Range()
 					go func() {
 						chunkPos := world.ChunkPos{pk.Position.X(), pk.Position.Z()}
-						c, err := chunk.NetworkDecode(airRID, pk.RawPayload, int(pk.SubChunkCount), oldFormat, dimension.Range())
-						if err == nil {
-							mu.Lock()
-							chunks[chunkPos] = c
-							mu.Unlock()
+						buf := bytes.NewBuffer(pk.RawPayload)
+						c, err := chunk.NetworkDecode(airRID, buf, int(pk.SubChunkCount), oldFormat, r)
+						if err != nil {
+							return
+						}
+						cache.Store(chunkPos, c)
+						// Chunks sent in full hold all sub chunks, including the empty ones not sent.
+						cache.MarkReceived(chunkPos, 1<<len(c.Sub())-1, true)
 
-							renderer.RerenderChunk(chunkPos)
+						// The biomes are followed by the border blocks, which are skipped, and the block entities of
+						// the chunk.
+						borderBlocks, err := buf.ReadByte()
+						if err != nil {
+							return
 						}
+						buf.Next(int(borderBlocks))
+						var blockEntities []map[string]interface{}
+						dec := nbt.NewDecoderWithEncoding(buf, nbt.NetworkLittleEndian)
+						for buf.Len() != 0 {
+							var blockEntity map[string]interface{}
+							if err := dec.Decode(&blockEntity); err != nil {
+								break
+							}
+							blockEntities = append(blockEntities, blockEntity)
+						}
+						cache.SetBlockEntities(chunkPos, blockEntities)
 					}()
 				}
 			}
@@ -314,6 +668,98 @@ func handleConn(log *logrus.Logger, conn *minecraft.Conn, listener *minecraft.Li
 	}()
 }
 
+// saveChunk saves the chunk at a position in the cache passed to the mcdb.Provider, together with its block entities.
+// If merge is true and the provider already has a chunk at the position, only the sub chunks and block entities that
+// were received replace those of the saved chunk, and all other sub chunks and block entities are kept.
+func saveChunk(prov *mcdb.Provider, cache *worldrenderer.CacheSource, pos world.ChunkPos, merge bool) error {
+	var (
+		saved              *chunk.Chunk
+		savedBlockEntities []map[string]interface{}
+	)
+	if merge {
+		c, exists, err := prov.LoadChunk(pos)
+		if err != nil {
+			return fmt.Errorf("error loading chunk %v: %w", pos, err)
+		}
+		if exists {
+			if savedBlockEntities, err = prov.LoadBlockNBT(pos); err != nil {
+				return fmt.Errorf("error loading block entities of chunk %v: %w", pos, err)
+			}
+			saved = c
+		}
+	}
+	subs, biomes := cache.Received(pos)
+	blockEntities := cache.BlockEntities(pos)
+
+	var err error
+	cache.Modify(pos, func(c *chunk.Chunk) {
+		c.Compact()
+		if saved == nil {
+			err = prov.SaveChunk(pos, c)
+			return
+		}
+		saved.Merge(c, subs, biomes)
+		err = prov.SaveChunk(pos, saved)
+
+		// Block entities in sub chunks that were not received are kept.
+		for _, data := range savedBlockEntities {
+			if _, y, _, ok := nbtPos(data); ok && y >= c.Range().Min() && y <= c.Range().Max() && subs&(1<<c.SubIndex(int16(y))) == 0 {
+				blockEntities = append(blockEntities, data)
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error saving chunk %v: %w", pos, err)
+	}
+	if err := prov.SaveBlockNBT(pos, blockEntities); err != nil {
+		return fmt.Errorf("error saving block entities of chunk %v: %w", pos, err)
+	}
+	return nil
+}
+
+// subChunkMask returns a bit mask of the indices of the sub chunks within the cube.Range passed that hold blocks
+// between the minimum and maximum Y coordinates passed.
+func subChunkMask(r cube.Range, minY, maxY int) uint64 {
+	var mask uint64
+	for y := minY &^ 15; y <= maxY; y += 16 {
+		if y >= r.Min() && y <= r.Max() {
+			mask |= 1 << ((y - r.Min()) >> 4)
+		}
+	}
+	return mask
+}
+
+// dimensionByID returns the world.Dimension with the ID passed. If no dimension with the ID exists, world.Overworld is
+// returned.
+func dimensionByID(id int32) world.Dimension {
+	if dim, ok := world.DimensionByID(int(id)); ok {
+		return dim
+	}
+	return world.Overworld
+}
+
+// blockPos returns the position of the block that a player with the position passed is standing in.
+func blockPos(pos mgl32.Vec3) cube.Pos {
+	return cube.Pos{
+		int(math.Floor(float64(pos.X()))),
+		int(math.Floor(float64(pos.Y()) - 1.62)),
+		int(math.Floor(float64(pos.Z()))),
+	}
+}
+
+// nbtPos returns the position held in the x, y and z fields of the NBT data of a block entity.
+func nbtPos(data map[string]interface{}) (x, y, z int, ok bool) {
+	bx, okX := data["x"].(int32)
+	by, okY := data["y"].(int32)
+	bz, okZ := data["z"].(int32)
+	return int(bx), int(by), int(bz), okX && okY && okZ
+}
+
+// vec64 converts a mgl32.Vec3 to a mgl64.Vec3.
+func vec64(v mgl32.Vec3) mgl64.Vec3 {
+	return mgl64.Vec3{float64(v[0]), float64(v[1]), float64(v[2])}
+}
+
 type config struct {
 	Connection struct {
 		LocalAddress  string
@@ -322,6 +768,11 @@ type config struct {
 	Downloader struct {
 		OutputDirectory string
 	}
+	Renderer struct {
+		TimeLapseInterval int
+		ResourcePack      string
+		ColourOverrides   string
+	}
 }
 
 // readConfig reads the configuration from the config.toml file, or creates the file if it does not yet exist.
@@ -329,6 +780,7 @@ func readConfig() (config, error) {
 	c := config{}
 	c.Connection.LocalAddress = ":19132"
 	c.Connection.RemoteAddress = "play.lbsg.net:19132"
+	c.Renderer.TimeLapseInterval = 5
 	if _, err := os.Stat("config.toml"); os.IsNotExist(err) {
 		data, err := toml.Marshal(c)
 		if err != nil {
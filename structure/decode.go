@@ -0,0 +1,108 @@
+package structure
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"io"
+	"strconv"
+)
+
+// maxVolume is the maximum number of blocks a structure read using Decode may hold. Larger structures are rejected, so
+// that a corrupted size cannot exhaust memory when the blocks of the structure are allocated.
+const maxVolume = 1 << 24
+
+// Decode reads a Structure in the .mcstructure format from the io.Reader passed. Block states in the palette of the
+// structure that are not known are read as structure void, and their names are returned in unknown. The entities in
+// the structure are not read, as they cannot be placed in a world.
+func Decode(r io.Reader) (s *Structure, unknown []string, err error) {
+	var m map[string]interface{}
+	if err := nbt.NewDecoderWithEncoding(r, nbt.LittleEndian).Decode(&m); err != nil {
+		return nil, nil, fmt.Errorf("error decoding structure: %w", err)
+	}
+	size, ok := int32List(m["size"])
+	if !ok || len(size) != 3 || size[0] < 0 || size[1] < 0 || size[2] < 0 {
+		return nil, nil, fmt.Errorf("structure has invalid size %v", m["size"])
+	}
+	volume := int64(1)
+	for _, l := range size {
+		// The volume is checked after every multiplication, so that it cannot overflow.
+		if volume *= int64(l); volume > maxVolume {
+			return nil, nil, fmt.Errorf("structure size %v exceeds the maximum volume of %v blocks", size, maxVolume)
+		}
+	}
+	s = New([3]int{int(size[0]), int(size[1]), int(size[2])})
+	if origin, ok := int32List(m["structure_world_origin"]); ok && len(origin) == 3 {
+		s.origin = [3]int{int(origin[0]), int(origin[1]), int(origin[2])}
+	}
+
+	data, _ := m["structure"].(map[string]interface{})
+	palettes, _ := data["palette"].(map[string]interface{})
+	palette, _ := palettes["default"].(map[string]interface{})
+
+	entries, _ := palette["block_palette"].([]interface{})
+	rids := make([]uint32, len(entries))
+	for i, entry := range entries {
+		state, _ := entry.(map[string]interface{})
+		name, _ := state["name"].(string)
+		properties, _ := state["states"].(map[string]interface{})
+		rid, ok := chunk.StateToRuntimeID(name, properties)
+		if !ok {
+			rid = void
+			unknown = append(unknown, name)
+		}
+		rids[i] = rid
+	}
+
+	layers, _ := data["block_indices"].([]interface{})
+	for layer, l := range layers {
+		if layer >= len(s.blocks) {
+			break
+		}
+		indices, ok := int32List(l)
+		if !ok || (len(indices) != 0 && len(indices) != len(s.blocks[layer])) {
+			return nil, nil, fmt.Errorf("structure has invalid block indices on layer %v", layer)
+		}
+		for i, index := range indices {
+			if index < 0 {
+				continue
+			}
+			if int(index) >= len(rids) {
+				return nil, nil, fmt.Errorf("structure has block index %v out of palette bounds", index)
+			}
+			s.blocks[layer][i] = rids[index]
+		}
+	}
+
+	positionData, _ := palette["block_position_data"].(map[string]interface{})
+	for k, v := range positionData {
+		index, err := strconv.Atoi(k)
+		if err != nil || index < 0 || index >= len(s.blocks[0]) {
+			continue
+		}
+		entry, _ := v.(map[string]interface{})
+		if blockEntity, ok := entry["block_entity_data"].(map[string]interface{}); ok {
+			s.blockEntities[index] = blockEntity
+		}
+	}
+	return s, unknown, nil
+}
+
+// int32List converts a list of ints decoded from NBT to an []int32.
+func int32List(v interface{}) ([]int32, bool) {
+	switch v := v.(type) {
+	case []int32:
+		return v, true
+	case []interface{}:
+		list := make([]int32, len(v))
+		for i, e := range v {
+			n, ok := e.(int32)
+			if !ok {
+				return nil, false
+			}
+			list[i] = n
+		}
+		return list, true
+	}
+	return nil, false
+}
@@ -0,0 +1,100 @@
+package structure
+
+import (
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+	"io"
+	"strconv"
+)
+
+// formatVersion is the version of the .mcstructure format written.
+const formatVersion = 1
+
+// mcstructure is the NBT layout of a .mcstructure file.
+type mcstructure struct {
+	FormatVersion int32         `nbt:"format_version"`
+	Size          []int32       `nbt:"size"`
+	Structure     structureData `nbt:"structure"`
+	Origin        []int32       `nbt:"structure_world_origin"`
+}
+
+// structureData is the structure compound of a .mcstructure file, which holds the blocks and entities.
+type structureData struct {
+	// BlockIndices holds a list of indices into the block palette for both layers. An index of -1 means that the
+	// block is structure void.
+	BlockIndices [][]int32                   `nbt:"block_indices"`
+	Entities     []map[string]interface{}    `nbt:"entities"`
+	Palette      map[string]structurePalette `nbt:"palette"`
+}
+
+// structurePalette is a palette of a .mcstructure file. Only the default palette is used.
+type structurePalette struct {
+	BlockPalette      []paletteEntry         `nbt:"block_palette"`
+	BlockPositionData map[string]interface{} `nbt:"block_position_data"`
+}
+
+// paletteEntry is a block state in the block palette of a .mcstructure file.
+type paletteEntry struct {
+	Name    string                 `nbt:"name"`
+	States  map[string]interface{} `nbt:"states"`
+	Version int32                  `nbt:"version"`
+}
+
+// Encode writes the structure to the io.Writer passed in the .mcstructure format. An error is returned if the
+// structure holds a runtime ID that does not belong to a block state.
+func (s *Structure) Encode(w io.Writer) error {
+	var (
+		palette []paletteEntry
+		indices = make(map[uint32]int32)
+		data    = structureData{
+			BlockIndices: make([][]int32, len(s.blocks)),
+			Entities:     s.entities,
+		}
+	)
+	if data.Entities == nil {
+		data.Entities = []map[string]interface{}{}
+	}
+	for layer, blocks := range s.blocks {
+		data.BlockIndices[layer] = make([]int32, len(blocks))
+		for i, rid := range blocks {
+			if rid == void {
+				data.BlockIndices[layer][i] = -1
+				continue
+			}
+			index, ok := indices[rid]
+			if !ok {
+				name, properties, found := chunk.RuntimeIDToState(rid)
+				if !found {
+					return fmt.Errorf("cannot encode unknown runtime ID %v", rid)
+				}
+				if properties == nil {
+					properties = map[string]interface{}{}
+				}
+				index = int32(len(palette))
+				indices[rid] = index
+				palette = append(palette, paletteEntry{Name: name, States: properties, Version: chunk.CurrentBlockVersion})
+			}
+			data.BlockIndices[layer][i] = index
+		}
+	}
+	if palette == nil {
+		palette = []paletteEntry{}
+	}
+
+	positionData := make(map[string]interface{}, len(s.blockEntities))
+	for index, blockEntity := range s.blockEntities {
+		positionData[strconv.Itoa(index)] = map[string]interface{}{"block_entity_data": blockEntity}
+	}
+	data.Palette = map[string]structurePalette{"default": {BlockPalette: palette, BlockPositionData: positionData}}
+
+	if err := nbt.NewEncoderWithEncoding(w, nbt.LittleEndian).Encode(mcstructure{
+		FormatVersion: formatVersion,
+		Size:          []int32{int32(s.size[0]), int32(s.size[1]), int32(s.size[2])},
+		Structure:     data,
+		Origin:        []int32{int32(s.origin[0]), int32(s.origin[1]), int32(s.origin[2])},
+	}); err != nil {
+		return fmt.Errorf("error encoding structure: %w", err)
+	}
+	return nil
+}
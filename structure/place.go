@@ -0,0 +1,71 @@
+package structure
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+)
+
+// Chunks returns the positions of all chunks that the structure covers when it is placed with its lowest corner at
+// the position passed.
+func (s *Structure) Chunks(at cube.Pos) []world.ChunkPos {
+	var positions []world.ChunkPos
+	for cx := at[0] >> 4; cx <= (at[0]+s.size[0]-1)>>4; cx++ {
+		for cz := at[2] >> 4; cz <= (at[2]+s.size[2]-1)>>4; cz++ {
+			positions = append(positions, world.ChunkPos{int32(cx), int32(cz)})
+		}
+	}
+	return positions
+}
+
+// PlaceChunk places the part of the structure that falls within the chunk at the position passed, with the lowest
+// corner of the structure at the position at. Structure void blocks leave the blocks of the chunk unchanged, and
+// blocks outside the range of the chunk are not placed. The block entities of the chunk are passed, and the block
+// entities of the chunk after placing the structure are returned: block entities of blocks replaced by the structure
+// are removed, and those of the structure are added with their position updated. Entities in the structure are not
+// placed.
+func (s *Structure) PlaceChunk(at cube.Pos, pos world.ChunkPos, c *chunk.Chunk, blockEntities []map[string]interface{}) []map[string]interface{} {
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	baseX, baseZ := int(pos.X())<<4, int(pos.Z())<<4
+	r := c.Range()
+
+	replaced := make(map[cube.Pos]struct{})
+	var placed []map[string]interface{}
+	for x := maxInt(at[0], baseX); x <= minInt(at[0]+s.size[0]-1, baseX+15); x++ {
+		for z := maxInt(at[2], baseZ); z <= minInt(at[2]+s.size[2]-1, baseZ+15); z++ {
+			for y := maxInt(at[1], r.Min()); y <= minInt(at[1]+s.size[1]-1, r.Max()); y++ {
+				sx, sy, sz := x-at[0], y-at[1], z-at[2]
+				rid, ok := s.Block(sx, sy, sz, 0)
+				if !ok {
+					continue
+				}
+				c.SetBlock(uint8(x&15), int16(y), uint8(z&15), 0, rid)
+				if rid, ok = s.Block(sx, sy, sz, 1); !ok {
+					rid = air
+				}
+				c.SetBlock(uint8(x&15), int16(y), uint8(z&15), 1, rid)
+				replaced[cube.Pos{x, y, z}] = struct{}{}
+
+				if blockEntity, ok := s.BlockEntity(sx, sy, sz); ok {
+					data := make(map[string]interface{}, len(blockEntity))
+					for k, v := range blockEntity {
+						data[k] = v
+					}
+					data["x"], data["y"], data["z"] = int32(x), int32(y), int32(z)
+					placed = append(placed, data)
+				}
+			}
+		}
+	}
+
+	result := make([]map[string]interface{}, 0, len(blockEntities)+len(placed))
+	for _, data := range blockEntities {
+		if x, y, z, ok := blockEntityPos(data); ok {
+			if _, ok := replaced[cube.Pos{x, y, z}]; ok {
+				continue
+			}
+		}
+		result = append(result, data)
+	}
+	return append(result, placed...)
+}
@@ -0,0 +1,217 @@
+package structure
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"math"
+)
+
+// void is the runtime ID used for blocks of a Structure that are structure void. Structure void blocks leave the
+// block in the world unchanged when the structure is placed.
+const void = math.MaxUint32
+
+// Structure is a cuboid of blocks with two layers, together with the block entities and entities in it. It may be
+// written to and read from Bedrock Edition .mcstructure files.
+type Structure struct {
+	size   [3]int
+	origin cube.Pos
+	// blocks holds the runtime IDs of the blocks in both layers of the structure, indexed by the index of their
+	// position. Blocks that are structure void have the runtime ID void.
+	blocks [2][]uint32
+	// blockEntities holds the NBT data of the block entities in the structure, indexed by the index of their position.
+	blockEntities map[int]map[string]interface{}
+	entities      []map[string]interface{}
+}
+
+// New creates a new Structure with the size passed, where every block is structure void.
+func New(size [3]int) *Structure {
+	s := &Structure{size: size, blockEntities: make(map[int]map[string]interface{})}
+	for layer := range s.blocks {
+		s.blocks[layer] = make([]uint32, size[0]*size[1]*size[2])
+		for i := range s.blocks[layer] {
+			s.blocks[layer][i] = void
+		}
+	}
+	return s
+}
+
+// Size returns the width, height and length of the structure.
+func (s *Structure) Size() [3]int {
+	return s.size
+}
+
+// Origin returns the position in the world that the structure was read from.
+func (s *Structure) Origin() cube.Pos {
+	return s.origin
+}
+
+// Block returns the runtime ID of the block at a position relative to the structure on the layer passed. False is
+// returned if the block is structure void.
+func (s *Structure) Block(x, y, z int, layer uint8) (uint32, bool) {
+	rid := s.blocks[layer][s.index(x, y, z)]
+	return rid, rid != void
+}
+
+// SetBlock sets the runtime ID of the block at a position relative to the structure on the layer passed.
+func (s *Structure) SetBlock(x, y, z int, layer uint8, rid uint32) {
+	s.blocks[layer][s.index(x, y, z)] = rid
+}
+
+// BlockEntity returns the NBT data of the block entity at a position relative to the structure. False is returned if
+// there is no block entity at the position.
+func (s *Structure) BlockEntity(x, y, z int) (map[string]interface{}, bool) {
+	data, ok := s.blockEntities[s.index(x, y, z)]
+	return data, ok
+}
+
+// SetBlockEntity sets the NBT data of the block entity at a position relative to the structure. Passing nil removes
+// the block entity.
+func (s *Structure) SetBlockEntity(x, y, z int, data map[string]interface{}) {
+	if data == nil {
+		delete(s.blockEntities, s.index(x, y, z))
+		return
+	}
+	s.blockEntities[s.index(x, y, z)] = data
+}
+
+// Entities returns the NBT data of all entities in the structure.
+func (s *Structure) Entities() []map[string]interface{} {
+	return s.entities
+}
+
+// index returns the index of a position relative to the structure in its blocks. Positions are ordered by X, then Y
+// and then Z, as done in .mcstructure files.
+func (s *Structure) index(x, y, z int) int {
+	return (x*s.size[1]+y)*s.size[2] + z
+}
+
+// Source is a source of chunks that a Structure may be read from. Chunks returned by a Source are locked using their
+// embedded mutex while they are read.
+type Source interface {
+	// Chunk returns the chunk at the position passed. False is returned if the source has no chunk at the position.
+	Chunk(pos world.ChunkPos) (*chunk.Chunk, bool)
+}
+
+// BlockEntitySource is a Source that also holds the block entities of its chunks. If the Source passed to Read
+// implements BlockEntitySource, block entities are read into the structure.
+type BlockEntitySource interface {
+	Source
+	// BlockEntities returns the NBT data of all block entities in the chunk at the position passed. The NBT data
+	// holds the world position of the block entity in its x, y and z fields.
+	BlockEntities(pos world.ChunkPos) []map[string]interface{}
+}
+
+// EntitySource is a Source that also holds the entities in its chunks. If the Source passed to Read implements
+// EntitySource, entities are read into the structure.
+type EntitySource interface {
+	Source
+	// Entities returns the NBT data of all entities in the chunk at the position passed. The NBT data holds the world
+	// position of the entity in its Pos field.
+	Entities(pos world.ChunkPos) []map[string]interface{}
+}
+
+// Read reads the cuboid between the two corners passed from a Source into a new Structure. Blocks in chunks that are
+// not present in the Source, or outside the range of their chunk, are read as structure void, as are blocks on the
+// second layer that are air. Entities are read if their position is within the cuboid.
+func Read(src Source, a, b cube.Pos) *Structure {
+	min := cube.Pos{minInt(a[0], b[0]), minInt(a[1], b[1]), minInt(a[2], b[2])}
+	max := cube.Pos{maxInt(a[0], b[0]), maxInt(a[1], b[1]), maxInt(a[2], b[2])}
+	s := New([3]int{max[0] - min[0] + 1, max[1] - min[1] + 1, max[2] - min[2] + 1})
+	s.origin = min
+
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	blockEntitySrc, hasBlockEntities := src.(BlockEntitySource)
+	entitySrc, hasEntities := src.(EntitySource)
+	for cx := min[0] >> 4; cx <= max[0]>>4; cx++ {
+		for cz := min[2] >> 4; cz <= max[2]>>4; cz++ {
+			pos := world.ChunkPos{int32(cx), int32(cz)}
+			c, ok := src.Chunk(pos)
+			if !ok {
+				continue
+			}
+			c.Lock()
+			r := c.Range()
+			for x := maxInt(min[0], cx<<4); x <= minInt(max[0], cx<<4+15); x++ {
+				for z := maxInt(min[2], cz<<4); z <= minInt(max[2], cz<<4+15); z++ {
+					for y := maxInt(min[1], r.Min()); y <= minInt(max[1], r.Max()); y++ {
+						s.SetBlock(x-min[0], y-min[1], z-min[2], 0, c.Block(uint8(x&15), int16(y), uint8(z&15), 0))
+						if rid := c.Block(uint8(x&15), int16(y), uint8(z&15), 1); rid != air {
+							s.SetBlock(x-min[0], y-min[1], z-min[2], 1, rid)
+						}
+					}
+				}
+			}
+			c.Unlock()
+
+			if hasBlockEntities {
+				for _, data := range blockEntitySrc.BlockEntities(pos) {
+					x, y, z, ok := blockEntityPos(data)
+					if !ok || x < min[0] || y < min[1] || z < min[2] || x > max[0] || y > max[1] || z > max[2] {
+						continue
+					}
+					s.SetBlockEntity(x-min[0], y-min[1], z-min[2], data)
+				}
+			}
+			if hasEntities {
+				for _, data := range entitySrc.Entities(pos) {
+					x, y, z, ok := entityPos(data)
+					if !ok || x < min[0] || y < min[1] || z < min[2] || x > max[0] || y > max[1] || z > max[2] {
+						continue
+					}
+					s.entities = append(s.entities, data)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// blockEntityPos returns the world position held in the x, y and z fields of the NBT data of a block entity.
+func blockEntityPos(data map[string]interface{}) (x, y, z int, ok bool) {
+	bx, okX := data["x"].(int32)
+	by, okY := data["y"].(int32)
+	bz, okZ := data["z"].(int32)
+	return int(bx), int(by), int(bz), okX && okY && okZ
+}
+
+// entityPos returns the world position of the block that the position held in the Pos field of the NBT data of an
+// entity is in.
+func entityPos(data map[string]interface{}) (x, y, z int, ok bool) {
+	var pos [3]float32
+	switch p := data["Pos"].(type) {
+	case []float32:
+		if len(p) != 3 {
+			return 0, 0, 0, false
+		}
+		copy(pos[:], p)
+	case []interface{}:
+		if len(p) != 3 {
+			return 0, 0, 0, false
+		}
+		for i, v := range p {
+			if pos[i], ok = v.(float32); !ok {
+				return 0, 0, 0, false
+			}
+		}
+	default:
+		return 0, 0, 0, false
+	}
+	return int(math.Floor(float64(pos[0]))), int(math.Floor(float64(pos[1]))), int(math.Floor(float64(pos[2]))), true
+}
+
+// minInt returns the smallest of the two integers passed.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the largest of the two integers passed.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
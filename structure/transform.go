@@ -0,0 +1,200 @@
+package structure
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"strings"
+)
+
+// Rotate returns a copy of the structure rotated clockwise by 90 degrees, as seen from above, the amount of times
+// passed. The directions held in the states of blocks, such as those of stairs and doors, are rotated too.
+func (s *Structure) Rotate(times int) *Structure {
+	times = ((times % 4) + 4) % 4
+	rotated := s
+	for i := 0; i < times; i++ {
+		length := rotated.size[2]
+		rotated = rotated.transform([3]int{rotated.size[2], rotated.size[1], rotated.size[0]}, func(x, z int) (int, int) {
+			return length - 1 - z, x
+		}, stateTransformer{direction: cube.Direction.RotateRight, swapAxes: true})
+	}
+	return rotated
+}
+
+// Mirror returns a copy of the structure mirrored along the axis passed, which must be either cube.X or cube.Z.
+// Mirroring along cube.X flips the X coordinates of the blocks, so that east and west are swapped, and mirroring
+// along cube.Z flips the Z coordinates, so that north and south are swapped.
+func (s *Structure) Mirror(axis cube.Axis) *Structure {
+	if axis == cube.X {
+		return s.transform(s.size, func(x, z int) (int, int) {
+			return s.size[0] - 1 - x, z
+		}, stateTransformer{direction: flip(cube.East, cube.West), signFlip: 16, mirror: true})
+	}
+	return s.transform(s.size, func(x, z int) (int, int) {
+		return x, s.size[2] - 1 - z
+	}, stateTransformer{direction: flip(cube.North, cube.South), signFlip: 8, mirror: true})
+}
+
+// transform returns a copy of the structure with the size passed, where every block is moved to the horizontal
+// position returned by the function passed and has its state transformed by the stateTransformer. The entities of the
+// structure are not kept, as their positions are not transformed.
+func (s *Structure) transform(size [3]int, pos func(x, z int) (int, int), t stateTransformer) *Structure {
+	n := New(size)
+	n.origin = s.origin
+
+	transformed := make(map[uint32]uint32)
+	for x := 0; x < s.size[0]; x++ {
+		for z := 0; z < s.size[2]; z++ {
+			nx, nz := pos(x, z)
+			for y := 0; y < s.size[1]; y++ {
+				for layer := uint8(0); layer < 2; layer++ {
+					rid, ok := s.Block(x, y, z, layer)
+					if !ok {
+						continue
+					}
+					newRID, ok := transformed[rid]
+					if !ok {
+						newRID = t.transform(rid)
+						transformed[rid] = newRID
+					}
+					n.SetBlock(nx, y, nz, layer, newRID)
+				}
+				if blockEntity, ok := s.BlockEntity(x, y, z); ok {
+					n.SetBlockEntity(nx, y, nz, blockEntity)
+				}
+			}
+		}
+	}
+	return n
+}
+
+// flip returns a function swapping the two directions passed, leaving other directions unchanged.
+func flip(a, b cube.Direction) func(d cube.Direction) cube.Direction {
+	return func(d cube.Direction) cube.Direction {
+		switch d {
+		case a:
+			return b
+		case b:
+			return a
+		}
+		return d
+	}
+}
+
+// stateTransformer transforms the properties of block states holding a direction or axis.
+type stateTransformer struct {
+	// direction transforms a horizontal direction.
+	direction func(d cube.Direction) cube.Direction
+	// swapAxes is true if the X and Z axes are swapped by the transformation.
+	swapAxes bool
+	// signFlip is the value that the rotation of standing signs and banners, which ranges from 0 to 15 starting south
+	// and going clockwise, is subtracted from to mirror it. If 0, the rotation is turned 90 degrees clockwise instead.
+	signFlip int32
+	// mirror is true if the transformation mirrors the structure, which moves the hinges of doors to the other side.
+	mirror bool
+}
+
+var (
+	// horizontalDirections holds the directions in the order of the direction property of blocks such as beds and
+	// fence gates.
+	horizontalDirections = []cube.Direction{cube.South, cube.West, cube.North, cube.East}
+	// weirdoDirections holds the directions in the order of the weirdo_direction property of stairs and the
+	// direction property of trapdoors.
+	weirdoDirections = []cube.Direction{cube.East, cube.West, cube.South, cube.North}
+	// doorDirections holds the directions in the order of the direction property of doors.
+	doorDirections = []cube.Direction{cube.East, cube.South, cube.West, cube.North}
+	// faceDirections maps the horizontal faces in the order of the facing_direction property to directions.
+	faceDirections = map[int32]cube.Direction{2: cube.North, 3: cube.South, 4: cube.West, 5: cube.East}
+	// directionNames holds the names of directions used by properties with string values.
+	directionNames = map[string]cube.Direction{"north": cube.North, "south": cube.South, "west": cube.West, "east": cube.East}
+)
+
+// transform returns the runtime ID of the block state with the runtime ID passed after transforming its properties.
+// If the transformed state does not exist, the runtime ID passed is returned.
+func (t stateTransformer) transform(rid uint32) uint32 {
+	name, properties, ok := chunk.RuntimeIDToState(rid)
+	if !ok || len(properties) == 0 {
+		return rid
+	}
+	newProperties := make(map[string]interface{}, len(properties))
+	for k, v := range properties {
+		newProperties[k] = t.property(name, k, v)
+	}
+	if newRID, ok := chunk.StateToRuntimeID(name, newProperties); ok {
+		return newRID
+	}
+	return rid
+}
+
+// property returns the transformed value of a property of the block with the name passed.
+func (t stateTransformer) property(name, k string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case int32:
+		switch k {
+		case "direction":
+			order := horizontalDirections
+			if strings.HasSuffix(name, "trapdoor") {
+				order = weirdoDirections
+			} else if strings.HasSuffix(name, "_door") {
+				order = doorDirections
+			}
+			return t.index(order, v)
+		case "weirdo_direction":
+			return t.index(weirdoDirections, v)
+		case "facing_direction":
+			d, ok := faceDirections[v]
+			if !ok {
+				return v
+			}
+			d = t.direction(d)
+			for face, fd := range faceDirections {
+				if fd == d {
+					return face
+				}
+			}
+		case "ground_sign_direction":
+			if t.signFlip == 0 {
+				return (v + 4) & 15
+			}
+			return (t.signFlip - v) & 15
+		}
+	case uint8:
+		if k == "door_hinge_bit" && t.mirror {
+			return v ^ 1
+		}
+	case string:
+		switch k {
+		case "pillar_axis":
+			if t.swapAxes && v == "x" {
+				return "z"
+			} else if t.swapAxes && v == "z" {
+				return "x"
+			}
+		case "minecraft:cardinal_direction", "minecraft:facing_direction", "minecraft:block_face", "torch_facing_direction":
+			d, ok := directionNames[v]
+			if !ok {
+				return v
+			}
+			d = t.direction(d)
+			for dn, nd := range directionNames {
+				if nd == d {
+					return dn
+				}
+			}
+		}
+	}
+	return v
+}
+
+// index transforms a direction stored as an index into the order of directions passed.
+func (t stateTransformer) index(order []cube.Direction, v int32) interface{} {
+	if v < 0 || int(v) >= len(order) {
+		return v
+	}
+	d := t.direction(order[v])
+	for i, od := range order {
+		if od == d {
+			return int32(i)
+		}
+	}
+	return v
+}
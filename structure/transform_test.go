@@ -0,0 +1,60 @@
+package structure
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"testing"
+)
+
+// TestTransformIdentity tests that rotating a structure four times and mirroring it twice along the same axis result
+// in the structure it started as, for blocks of which the state holds a direction.
+func TestTransformIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+	}{
+		{name: "minecraft:oak_stairs", properties: map[string]interface{}{"upside_down_bit": uint8(0), "weirdo_direction": int32(1)}},
+		{name: "minecraft:wooden_door", properties: map[string]interface{}{"direction": int32(2), "door_hinge_bit": uint8(1), "open_bit": uint8(0), "upper_block_bit": uint8(0)}},
+		{name: "minecraft:standing_sign", properties: map[string]interface{}{"ground_sign_direction": int32(3)}},
+		{name: "minecraft:wall_sign", properties: map[string]interface{}{"facing_direction": int32(4)}},
+		{name: "minecraft:log", properties: map[string]interface{}{"old_log_type": "oak", "pillar_axis": "x"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rid, ok := chunk.StateToRuntimeID(test.name, test.properties)
+			if !ok {
+				t.Fatalf("block state %v %v does not exist", test.name, test.properties)
+			}
+			// The structure is not square and the block is placed off centre, so that transforming it moves the block.
+			s := New([3]int{3, 1, 2})
+			s.SetBlock(0, 0, 1, 0, rid)
+
+			if rotated, _ := s.Rotate(1).Block(0, 0, 0, 0); rotated == rid {
+				t.Fatalf("rotating the structure once left the block state unchanged")
+			}
+			if rotated := s.Rotate(4); !sameBlocks(rotated, s) {
+				t.Errorf("rotating the structure four times changed it")
+			}
+			for _, axis := range []cube.Axis{cube.X, cube.Z} {
+				if mirrored := s.Mirror(axis).Mirror(axis); !sameBlocks(mirrored, s) {
+					t.Errorf("mirroring the structure twice along %v changed it", axis)
+				}
+			}
+		})
+	}
+}
+
+// sameBlocks checks if the structures passed have the same size and the same blocks on both layers.
+func sameBlocks(a, b *Structure) bool {
+	if a.Size() != b.Size() {
+		return false
+	}
+	for layer := range a.blocks {
+		for i, rid := range a.blocks[layer] {
+			if b.blocks[layer][i] != rid {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,296 @@
+package worlddiff
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"sort"
+	"strings"
+)
+
+// Provider is a provider of saved chunks that may be compared. It is implemented by mcdb.Provider.
+type Provider interface {
+	// LoadChunk loads the chunk at the position passed. If it doesn't exist, exists is false.
+	LoadChunk(pos world.ChunkPos) (c *chunk.Chunk, exists bool, err error)
+	// LoadSubChunkData loads the encoded data of the sub chunks of the chunk at the position passed, ordered from the
+	// bottom of the dimension up. The data of sub chunks that are not present is nil.
+	LoadSubChunkData(pos world.ChunkPos) ([][]byte, error)
+	// Positions returns the positions of all chunks in the provider.
+	Positions() ([]world.ChunkPos, error)
+}
+
+// Change is a kind of change made to a block. Changes are flags, so that the changes in a column of blocks may be
+// combined.
+type Change uint8
+
+const (
+	// Added is the change of a block that was air and no longer is.
+	Added Change = 1 << iota
+	// Removed is the change of a block that was not air and now is.
+	Removed
+	// Changed is the change of a block that was replaced by another block that is not air.
+	Changed
+)
+
+// String returns the name of the change.
+func (c Change) String() string {
+	switch c {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("Change(%d)", uint8(c))
+}
+
+// Block is a single block that differs between two worlds. Before and After hold the runtime IDs of the blocks on the
+// first and second layer.
+type Block struct {
+	Pos           cube.Pos
+	Change        Change
+	Before, After [2]uint32
+}
+
+// String formats the Block in the format x y z change before -> after. Blocks on the second layer are only included
+// if they are not air.
+func (b Block) String() string {
+	return fmt.Sprintf("%v %v %v %v %v -> %v", b.Pos.X(), b.Pos.Y(), b.Pos.Z(), b.Change, layersString(b.Before), layersString(b.After))
+}
+
+// layersString formats the block states on the first and second layer passed, joined by a plus sign if the block on
+// the second layer is not air.
+func layersString(layers [2]uint32) string {
+	s := stateString(layers[0])
+	if name, _, _ := chunk.RuntimeIDToState(layers[1]); name != "minecraft:air" {
+		s += "+" + stateString(layers[1])
+	}
+	return s
+}
+
+// stateString formats the block state with the runtime ID passed in the format name[key=value,key=value], with the
+// properties sorted by their keys.
+func stateString(rid uint32) string {
+	name, properties, ok := chunk.RuntimeIDToState(rid)
+	if !ok {
+		return fmt.Sprintf("unknown(%v)", rid)
+	}
+	if len(properties) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, properties[k]))
+	}
+	return name + "[" + strings.Join(pairs, ",") + "]"
+}
+
+// ChunkDiff holds the differences between the chunks at the same position in two worlds.
+type ChunkDiff struct {
+	// Pos is the position of the chunk.
+	Pos world.ChunkPos
+	// Added, Removed and Changed are the amount of blocks with each change in the chunk.
+	Added, Removed, Changed int
+	// Columns holds the changes of all blocks in every column of the chunk combined, indexed by x|z<<4.
+	Columns [256]Change
+	// Blocks holds the blocks that differ. It is only filled if the Differ was created to keep blocks.
+	Blocks []Block
+}
+
+// Total returns the total amount of blocks that differ in the chunk.
+func (d *ChunkDiff) Total() int {
+	return d.Added + d.Removed + d.Changed
+}
+
+// Diff holds the differences between two worlds.
+type Diff struct {
+	// Compared is the amount of chunks that were compared, which includes chunks that are present in only one of the
+	// worlds.
+	Compared int
+	// Chunks holds the chunks that differ, in order of their X and Z coordinates.
+	Chunks []*ChunkDiff
+	// Unchanged holds the positions of the chunks that were compared and found to be the same.
+	Unchanged []world.ChunkPos
+}
+
+// Added returns the total amount of blocks that were added.
+func (d *Diff) Added() (n int) {
+	for _, c := range d.Chunks {
+		n += c.Added
+	}
+	return n
+}
+
+// Removed returns the total amount of blocks that were removed.
+func (d *Diff) Removed() (n int) {
+	for _, c := range d.Chunks {
+		n += c.Removed
+	}
+	return n
+}
+
+// Changed returns the total amount of blocks that were changed.
+func (d *Diff) Changed() (n int) {
+	for _, c := range d.Chunks {
+		n += c.Changed
+	}
+	return n
+}
+
+// Differ compares the chunks of two worlds. Sub chunks whose encoded data is the same in both worlds are skipped
+// without decoding them, so that only the parts of the worlds that changed are compared block by block.
+type Differ struct {
+	air        uint32
+	r          cube.Range
+	keepBlocks bool
+}
+
+// NewDiffer creates a new Differ for worlds of the range passed. If keepBlocks is true, every block that differs is
+// kept in the ChunkDiff it is in.
+func NewDiffer(r cube.Range, keepBlocks bool) *Differ {
+	air, _ := chunk.StateToRuntimeID("minecraft:air", nil)
+	return &Differ{air: air, r: r, keepBlocks: keepBlocks}
+}
+
+// Diff compares all chunks present in either of the Providers passed, where a holds the older world and b the newer
+// one. Chunks that are present in only one of the worlds are compared as if they were filled with air in the other.
+func (d *Differ) Diff(a, b Provider) (*Diff, error) {
+	positionsA, err := a.Positions()
+	if err != nil {
+		return nil, err
+	}
+	positionsB, err := b.Positions()
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[world.ChunkPos]struct{}, len(positionsA))
+	positions := make([]world.ChunkPos, 0, len(positionsA))
+	for _, pos := range append(positionsA, positionsB...) {
+		if _, ok := found[pos]; !ok {
+			found[pos] = struct{}{}
+			positions = append(positions, pos)
+		}
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].X() != positions[j].X() {
+			return positions[i].X() < positions[j].X()
+		}
+		return positions[i].Z() < positions[j].Z()
+	})
+
+	diff := &Diff{Compared: len(positions)}
+	for _, pos := range positions {
+		c, err := d.DiffChunk(pos, a, b)
+		if err != nil {
+			return nil, err
+		}
+		if c.Total() == 0 {
+			diff.Unchanged = append(diff.Unchanged, pos)
+			continue
+		}
+		diff.Chunks = append(diff.Chunks, c)
+	}
+	return diff, nil
+}
+
+// DiffChunk compares the chunk at the position passed in the Providers a and b. The encoded data of the sub chunks is
+// compared first, and the chunks are only decoded if any of their sub chunks differ.
+func (d *Differ) DiffChunk(pos world.ChunkPos, a, b Provider) (*ChunkDiff, error) {
+	diff := &ChunkDiff{Pos: pos}
+	subsA, err := a.LoadSubChunkData(pos)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chunk %v: %w", pos, err)
+	}
+	subsB, err := b.LoadSubChunkData(pos)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chunk %v: %w", pos, err)
+	}
+	var differs []int
+	for i := 0; i < len(subsA) || i < len(subsB); i++ {
+		var subA, subB []byte
+		if i < len(subsA) {
+			subA = subsA[i]
+		}
+		if i < len(subsB) {
+			subB = subsB[i]
+		}
+		if !bytes.Equal(subA, subB) {
+			differs = append(differs, i)
+		}
+	}
+	if len(differs) == 0 {
+		return diff, nil
+	}
+
+	chunkA, err := d.loadChunk(pos, a)
+	if err != nil {
+		return nil, err
+	}
+	chunkB, err := d.loadChunk(pos, b)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range differs {
+		if i >= len(chunkA.Sub()) || i >= len(chunkB.Sub()) {
+			continue
+		}
+		d.diffSubChunk(diff, chunkA.Sub()[i], chunkB.Sub()[i], int(chunkA.SubY(int16(i))))
+	}
+	return diff, nil
+}
+
+// loadChunk loads the chunk at the position passed from the Provider, or returns an empty chunk if it does not exist.
+func (d *Differ) loadChunk(pos world.ChunkPos, p Provider) (*chunk.Chunk, error) {
+	c, ok, err := p.LoadChunk(pos)
+	if err != nil {
+		return nil, fmt.Errorf("error loading chunk %v: %w", pos, err)
+	}
+	if !ok {
+		return chunk.New(d.air, d.r), nil
+	}
+	return c, nil
+}
+
+// diffSubChunk compares the sub chunks a and b block by block and adds the blocks that differ to the ChunkDiff
+// passed. baseY is the Y coordinate of the lowest blocks in the sub chunks.
+func (d *Differ) diffSubChunk(diff *ChunkDiff, a, b *chunk.SubChunk, baseY int) {
+	baseX, baseZ := int(diff.Pos.X())<<4, int(diff.Pos.Z())<<4
+	for x := byte(0); x < 16; x++ {
+		for z := byte(0); z < 16; z++ {
+			for y := byte(0); y < 16; y++ {
+				before := [2]uint32{a.Block(x, y, z, 0), a.Block(x, y, z, 1)}
+				after := [2]uint32{b.Block(x, y, z, 0), b.Block(x, y, z, 1)}
+				if before == after {
+					continue
+				}
+				change := Changed
+				switch {
+				case before[0] == d.air && after[0] != d.air:
+					change, diff.Added = Added, diff.Added+1
+				case after[0] == d.air && before[0] != d.air:
+					change, diff.Removed = Removed, diff.Removed+1
+				default:
+					diff.Changed++
+				}
+				diff.Columns[int(x)|int(z)<<4] |= change
+				if d.keepBlocks {
+					diff.Blocks = append(diff.Blocks, Block{
+						Pos:    cube.Pos{baseX + int(x), baseY + int(y), baseZ + int(z)},
+						Change: change,
+						Before: before,
+						After:  after,
+					})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package worlddiff
+
+import (
+	"image"
+	"image/color"
+)
+
+var (
+	// unchangedColour is the colour of block columns in compared chunks that did not change.
+	unchangedColour = color.RGBA{R: 48, G: 48, B: 48, A: 255}
+	// addedColour is the colour of block columns in which blocks were only added.
+	addedColour = color.RGBA{R: 64, G: 200, B: 64, A: 255}
+	// removedColour is the colour of block columns in which blocks were only removed.
+	removedColour = color.RGBA{R: 220, G: 48, B: 48, A: 255}
+	// changedColour is the colour of block columns in which blocks were changed, or both added and removed.
+	changedColour = color.RGBA{R: 240, G: 200, B: 32, A: 255}
+)
+
+// Image renders the Diff top-down to an image with one pixel per block column. Columns in which blocks were only
+// added are green, columns in which blocks were only removed are red and columns with any other changes are yellow.
+// Columns of compared chunks without changes are dark grey, and areas outside the compared chunks are transparent.
+// The second value returned is the position of the block column at the top left of the image. Nil is returned if no
+// chunks were compared.
+func (d *Diff) Image() (*image.RGBA, [2]int) {
+	if d.Compared == 0 {
+		return nil, [2]int{}
+	}
+	minX, minZ, maxX, maxZ := int32(1<<31-1), int32(1<<31-1), int32(-1<<31), int32(-1<<31)
+	bound := func(x, z int32) {
+		if x < minX {
+			minX = x
+		}
+		if z < minZ {
+			minZ = z
+		}
+		if x > maxX {
+			maxX = x
+		}
+		if z > maxZ {
+			maxZ = z
+		}
+	}
+	for _, c := range d.Chunks {
+		bound(c.Pos.X(), c.Pos.Z())
+	}
+	for _, pos := range d.Unchanged {
+		bound(pos.X(), pos.Z())
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(maxX-minX+1)<<4, int(maxZ-minZ+1)<<4))
+	for _, pos := range d.Unchanged {
+		baseX, baseZ := int(pos.X()-minX)<<4, int(pos.Z()-minZ)<<4
+		for i := 0; i < 256; i++ {
+			img.SetRGBA(baseX+(i&15), baseZ+(i>>4), unchangedColour)
+		}
+	}
+	for _, c := range d.Chunks {
+		baseX, baseZ := int(c.Pos.X()-minX)<<4, int(c.Pos.Z()-minZ)<<4
+		for i, change := range c.Columns {
+			img.SetRGBA(baseX+(i&15), baseZ+(i>>4), columnColour(change))
+		}
+	}
+	return img, [2]int{int(minX) << 4, int(minZ) << 4}
+}
+
+// columnColour returns the colour of a block column with the combined changes passed.
+func columnColour(change Change) color.RGBA {
+	switch change {
+	case 0:
+		return unchangedColour
+	case Added:
+		return addedColour
+	case Removed:
+		return removedColour
+	}
+	return changedColour
+}
@@ -0,0 +1,215 @@
+package worldrenderer
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// captureMode is a kind of capture requested by pressing a capture key, which is taken the next time the renderer
+// is drawn.
+type captureMode int
+
+const (
+	// captureNone means that no capture was requested.
+	captureNone captureMode = iota
+	// captureView saves the current view of the renderer, without the HUD, to a PNG.
+	captureView
+	// captureArea saves all chunks rendered at one pixel per block to a PNG.
+	captureArea
+)
+
+// statusDuration is the duration that a status set using setStatus is shown in the HUD.
+const statusDuration = time.Second * 5
+
+// timeLapse is a time-lapse being recorded. Every frame is a capture of the view of the renderer, which is converted to
+// a paletted image in the background.
+type timeLapse struct {
+	started, last time.Time
+
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	frames []*image.Paletted
+}
+
+// SetTimeLapseInterval sets the interval at which frames are captured while a time-lapse is being recorded. The
+// default interval is five seconds.
+func (r *Renderer) SetTimeLapseInterval(interval time.Duration) {
+	if interval > 0 {
+		r.timeLapseInterval = interval
+	}
+}
+
+// toggleTimeLapse starts recording a time-lapse if none is being recorded. Otherwise, the time-lapse is stopped and
+// written to a GIF in the background.
+func (r *Renderer) toggleTimeLapse() {
+	if r.timeLapse == nil {
+		r.timeLapse = &timeLapse{started: time.Now()}
+		r.setStatus("recording time-lapse")
+		return
+	}
+	t := r.timeLapse
+	r.timeLapse = nil
+	r.setStatus("saving time-lapse...")
+
+	name := fmt.Sprintf("timelapse-%v.gif", t.started.Format("2006-01-02_15.04.05"))
+	go func() {
+		t.wg.Wait()
+		if err := writeFile(name, t.encode); err != nil {
+			r.setStatus(fmt.Sprintf("error saving time-lapse: %v", err))
+			return
+		}
+		r.setStatus(fmt.Sprintf("saved time-lapse to %v", name))
+	}()
+}
+
+// capture takes the capture requested using the capture keys, and a frame of the time-lapse being recorded if the
+// interval has passed since the last frame. It must be called before the HUD is drawn on the screen.
+func (r *Renderer) capture(screen *ebiten.Image) {
+	mode := r.captureMode
+	r.captureMode = captureNone
+
+	var view *image.RGBA
+	if mode == captureView || (r.timeLapse != nil && time.Since(r.timeLapse.last) >= r.timeLapseInterval) {
+		view = screenImage(screen)
+	}
+	switch mode {
+	case captureView:
+		r.save(fmt.Sprintf("screenshot-%v.png", time.Now().Format("2006-01-02_15.04.05")), view)
+	case captureArea:
+		if img := r.areaImage(); img != nil {
+			r.save(fmt.Sprintf("area-%v.png", time.Now().Format("2006-01-02_15.04.05")), img)
+		} else {
+			r.setStatus("no chunks rendered")
+		}
+	}
+	if t := r.timeLapse; t != nil && view != nil && time.Since(t.last) >= r.timeLapseInterval {
+		t.last = time.Now()
+		t.wg.Add(1)
+		go func() {
+			defer t.wg.Done()
+			frame := image.NewPaletted(view.Bounds(), palette.Plan9)
+			draw.Draw(frame, frame.Bounds(), view, image.Point{}, draw.Src)
+
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.frames = append(t.frames, frame)
+		}()
+	}
+}
+
+// save writes the image passed to a PNG file with the name passed in the background.
+func (r *Renderer) save(name string, img image.Image) {
+	r.setStatus(fmt.Sprintf("saving %v...", name))
+	go func() {
+		if err := writeFile(name, func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+			r.setStatus(fmt.Sprintf("error saving %v: %v", name, err))
+			return
+		}
+		r.setStatus(fmt.Sprintf("saved %v", name))
+	}()
+}
+
+// areaImage returns an image of all chunks currently rendered at one pixel per block. Nil is returned if no chunks
+// are rendered.
+func (r *Renderer) areaImage() *image.RGBA {
+	var bounds image.Rectangle
+	for pos, t := range r.tiles {
+		for i, rendered := range t.rendered {
+			if !rendered {
+				continue
+			}
+			x, z := int(pos[0])*tileSize+(i&(regionChunks-1))<<4, int(pos[1])*tileSize+(i/regionChunks)<<4
+			bounds = bounds.Union(image.Rect(x, z, x+16, z+16))
+		}
+	}
+	if bounds.Empty() {
+		return nil
+	}
+	img := image.NewRGBA(bounds)
+	for pos, t := range r.tiles {
+		origin := image.Pt(int(pos[0])*tileSize, int(pos[1])*tileSize)
+		draw.Draw(img, image.Rectangle{Min: origin, Max: origin.Add(image.Pt(tileSize, tileSize))}, t.pixels, image.Point{}, draw.Src)
+	}
+	// Move the image so that its top left corner is at 0, 0, as some image viewers do not handle other origins.
+	img.Rect = image.Rect(0, 0, bounds.Dx(), bounds.Dy())
+	return img
+}
+
+// screenImage reads the pixels currently drawn on the screen passed into an *image.RGBA.
+func screenImage(screen *ebiten.Image) *image.RGBA {
+	w, h := screen.Size()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBAModel.Convert(screen.At(x, y)))
+		}
+	}
+	return img
+}
+
+// encode encodes all frames of the time-lapse to the io.Writer passed as an animated GIF.
+func (t *timeLapse) encode(w io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.frames) == 0 {
+		return fmt.Errorf("time-lapse has no frames")
+	}
+	anim := &gif.GIF{Image: t.frames, Delay: make([]int, len(t.frames))}
+	for i, frame := range t.frames {
+		// Every frame is shown for half a second, and the last frame for two seconds before the animation loops.
+		anim.Delay[i] = 50
+		if i == len(t.frames)-1 {
+			anim.Delay[i] = 200
+		}
+		// Frames may have different sizes if the window was resized during the time-lapse.
+		size := frame.Bounds().Size()
+		if size.X > anim.Config.Width {
+			anim.Config.Width = size.X
+		}
+		if size.Y > anim.Config.Height {
+			anim.Config.Height = size.Y
+		}
+	}
+	anim.Config.ColorModel = color.Palette(palette.Plan9)
+	return gif.EncodeAll(w, anim)
+}
+
+// setStatus sets the status shown in the HUD for a few seconds.
+func (r *Renderer) setStatus(status string) {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	r.status, r.statusTime = status, time.Now()
+}
+
+// currentStatus returns the status set using setStatus, or an empty string if it was set too long ago.
+func (r *Renderer) currentStatus() string {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	if time.Since(r.statusTime) > statusDuration {
+		return ""
+	}
+	return r.status
+}
+
+// writeFile creates a file with the name passed and calls the function passed to write its contents.
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,140 @@
+package worldrenderer
+
+import (
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"image/color"
+	"math"
+)
+
+var (
+	// localPlayerColour is the colour of the marker of the player the renderer is centered on.
+	localPlayerColour = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	// playerColour is the colour of the markers of other players.
+	playerColour = color.RGBA{R: 64, G: 128, B: 255, A: 255}
+	// entityColour is the colour of the dots drawn for entities that are not players.
+	entityColour = color.RGBA{R: 255, G: 200, B: 0, A: 255}
+	// outlineColour is the colour used to outline markers so that they are visible on any background.
+	outlineColour = color.RGBA{A: 255}
+)
+
+// marker is a player or entity drawn on top of the rendered world.
+type marker struct {
+	// name is the name displayed above the marker. For players this is their username, for other entities it is their
+	// entity type.
+	name     string
+	uniqueID int64
+	pos      mgl64.Vec3
+	yaw      float64
+	player   bool
+}
+
+// UpdateLocalPlayer updates the position and yaw of the player that the renderer follows.
+func (r *Renderer) UpdateLocalPlayer(pos mgl64.Vec3, yaw float64) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	r.local = &marker{pos: pos, yaw: yaw, player: true}
+}
+
+// AddPlayer adds a marker for a player other than the local player with the runtime ID passed. The name of the
+// player is displayed above the marker.
+func (r *Renderer) AddPlayer(runtimeID uint64, uniqueID int64, name string, pos mgl64.Vec3, yaw float64) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	r.markers[runtimeID] = &marker{name: name, uniqueID: uniqueID, pos: pos, yaw: yaw, player: true}
+}
+
+// AddEntity adds a marker for a non-player entity with the runtime ID passed. Entities are only drawn if entity dots
+// are enabled.
+func (r *Renderer) AddEntity(runtimeID uint64, uniqueID int64, entityType string, pos mgl64.Vec3, yaw float64) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	r.markers[runtimeID] = &marker{name: entityType, uniqueID: uniqueID, pos: pos, yaw: yaw}
+}
+
+// MoveEntity moves the marker of the player or entity with the runtime ID passed. Nothing happens if no marker with
+// the runtime ID exists.
+func (r *Renderer) MoveEntity(runtimeID uint64, pos mgl64.Vec3, yaw float64) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	if m, ok := r.markers[runtimeID]; ok {
+		m.pos, m.yaw = pos, yaw
+	}
+}
+
+// EntityPosition returns the last known position and yaw of the player or entity with the runtime ID passed. False is
+// returned if no marker with the runtime ID exists.
+func (r *Renderer) EntityPosition(runtimeID uint64) (mgl64.Vec3, float64, bool) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	if m, ok := r.markers[runtimeID]; ok {
+		return m.pos, m.yaw, true
+	}
+	return mgl64.Vec3{}, 0, false
+}
+
+// RemoveEntity removes the marker of the player or entity with the unique ID passed.
+func (r *Renderer) RemoveEntity(uniqueID int64) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	for runtimeID, m := range r.markers {
+		if m.uniqueID == uniqueID {
+			delete(r.markers, runtimeID)
+		}
+	}
+}
+
+// ClearEntities removes the markers of all players and entities other than the local player.
+func (r *Renderer) ClearEntities() {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	r.markers = make(map[uint64]*marker)
+}
+
+// drawMarkers draws the markers of all entities, players and the local player on top of the screen.
+func (r *Renderer) drawMarkers(screen *ebiten.Image) {
+	w, h := screen.Size()
+
+	if dim, viewDim := r.dimensions(); dim != viewDim {
+		// The markers are in a different dimension than the one currently rendered.
+		return
+	}
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	if r.showEntities {
+		for _, m := range r.markers {
+			if !m.player {
+				x, y := r.screenPos(m.pos.X(), m.pos.Z(), w, h)
+				ebitenutil.DrawRect(screen, x-2, y-2, 4, 4, outlineColour)
+				ebitenutil.DrawRect(screen, x-1, y-1, 2, 2, entityColour)
+			}
+		}
+	}
+	for _, m := range r.markers {
+		if m.player {
+			r.drawPlayerMarker(screen, m, playerColour, w, h)
+		}
+	}
+	if r.local != nil {
+		r.drawPlayerMarker(screen, r.local, localPlayerColour, w, h)
+	}
+}
+
+// drawPlayerMarker draws a player marker with an arrow pointing in the direction the player is facing. If the player
+// has a name, a name tag is drawn next to the marker.
+func (r *Renderer) drawPlayerMarker(screen *ebiten.Image, m *marker, colour color.Color, w, h int) {
+	x, y := r.screenPos(m.pos.X(), m.pos.Z(), w, h)
+
+	// Minecraft's yaw starts at the positive Z axis and turns clockwise when viewed from above.
+	rad := mgl64.DegToRad(m.yaw)
+	dx, dy := -math.Sin(rad), math.Cos(rad)
+
+	ebitenutil.DrawLine(screen, x, y, x+dx*12, y+dy*12, outlineColour)
+	ebitenutil.DrawLine(screen, x+1, y, x+1+dx*12, y+dy*12, colour)
+	ebitenutil.DrawRect(screen, x-4, y-4, 8, 8, outlineColour)
+	ebitenutil.DrawRect(screen, x-3, y-3, 6, 6, colour)
+	if m.name != "" {
+		ebitenutil.DebugPrintAt(screen, m.name, int(x)+6, int(y)-18)
+	}
+}
@@ -0,0 +1,40 @@
+package worldrenderer
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"image/color"
+)
+
+// highlightColour is the colour of the markers drawn on highlighted blocks.
+var highlightColour = color.RGBA{R: 255, G: 0, B: 255, A: 255}
+
+// Highlight highlights the block positions passed on the renderer, replacing any positions highlighted previously.
+// Passing no positions clears all highlights.
+func (r *Renderer) Highlight(positions []cube.Pos) {
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	r.highlights = append([]cube.Pos(nil), positions...)
+}
+
+// drawHighlights draws a marker on top of every highlighted block column that is visible on the screen. Markers are
+// never drawn smaller than a few pixels, so that they remain visible while zoomed out.
+func (r *Renderer) drawHighlights(screen *ebiten.Image) {
+	w, h := screen.Size()
+	size := r.scale
+	if size < 4 {
+		size = 4
+	}
+
+	r.entityMu.Lock()
+	defer r.entityMu.Unlock()
+	for _, pos := range r.highlights {
+		x, y := r.screenPos(float64(pos.X())+0.5, float64(pos.Z())+0.5, w, h)
+		if x+size < 0 || y+size < 0 || x-size > float64(w) || y-size > float64(h) {
+			continue
+		}
+		ebitenutil.DrawRect(screen, x-size/2-1, y-size/2-1, size+2, size+2, outlineColour)
+		ebitenutil.DrawRect(screen, x-size/2, y-size/2, size, size, highlightColour)
+	}
+}
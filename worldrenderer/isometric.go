@@ -0,0 +1,342 @@
+package worldrenderer
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+)
+
+// air is the runtime ID of air.
+var air, _ = chunk.StateToRuntimeID("minecraft:air", nil)
+
+// isometricChunk is a chunk rendered isometrically. The image is positioned in isometric pixel space, meaning the
+// bounds of the image are the bounds the chunk occupies in the full isometric render.
+type isometricChunk struct {
+	img   *image.RGBA
+	depth int32
+}
+
+// renderIsometricChunk renders the chunk at the position passed isometrically, viewed from the rotation passed, using
+// the colours of the Palette passed. Only blocks at or below the ceiling passed are rendered. Faces of blocks are only
+// drawn if they are visible, which requires the neighbouring chunks to be present in the map passed. Nil is returned
+// if the chunk has no blocks.
+func renderIsometricChunk(p *Palette, rot cube.Direction, ceiling int16, pos world.ChunkPos, chunks map[world.ChunkPos]*chunk.Chunk) *isometricChunk {
+	ch := chunks[pos]
+	minY, maxY, ok := filledRange(ch, ceiling)
+	if !ok {
+		return nil
+	}
+
+	cx, cz := rotateColumn(int(pos.X()), int(pos.Z()), rot)
+	originX, originY := (cx-cz)*32, (cx+cz)*16
+	img := image.NewRGBA(image.Rect(
+		originX-30, originY-2*maxY,
+		originX+34, originY+34-2*minY,
+	))
+
+	// Iterating over the columns by the sum of their view coordinates ensures that blocks closer to the viewer are
+	// always drawn after blocks further away.
+	for sum := 0; sum <= 30; sum++ {
+		for vx := 0; vx <= sum; vx++ {
+			vz := sum - vx
+			if vx > 15 || vz > 15 {
+				continue
+			}
+			x, z := rotateLocal(vx, vz, (4-rotationSteps(rot))%4)
+			px, py := originX+(vx-vz)*2, originY+sum
+			for y := minY; y <= maxY; y++ {
+				worldPos := cube.Pos{int(pos.X())<<4 | x, y, int(pos.Z())<<4 | z}
+				rid := ch.Block(uint8(x), int16(y), uint8(z), 0)
+				if rid == air {
+					continue
+				}
+				drawIsometricBlock(p, img, px, py-2*y, rid, worldPos, rot, ceiling, chunks)
+			}
+		}
+	}
+	return &isometricChunk{img: img, depth: int32(cx + cz)}
+}
+
+// drawIsometricBlock draws the visible faces of the block with the runtime ID passed onto the image, with the top
+// left corner of the block at the x and y passed.
+func drawIsometricBlock(p *Palette, img *image.RGBA, x, y int, rid uint32, pos cube.Pos, rot cube.Direction, ceiling int16, chunks map[world.ChunkPos]*chunk.Chunk) {
+	colour, _ := p.colour(rid)
+
+	up, left, right := cube.FaceUp, cube.FaceSouth, cube.FaceEast
+	for i := 0; i < rotationSteps(rot); i++ {
+		left, right = left.RotateLeft(), right.RotateLeft()
+	}
+	if faceVisible(pos, up, ceiling, chunks) {
+		c := shade(colour, faceShade(up))
+		for dx := 0; dx < 4; dx++ {
+			img.SetRGBA(x+dx, y, c)
+			img.SetRGBA(x+dx, y+1, c)
+		}
+	}
+	if faceVisible(pos, left, ceiling, chunks) {
+		c := shade(colour, faceShade(left))
+		for dx := 0; dx < 2; dx++ {
+			img.SetRGBA(x+dx, y+2, c)
+			img.SetRGBA(x+dx, y+3, c)
+		}
+	}
+	if faceVisible(pos, right, ceiling, chunks) {
+		c := shade(colour, faceShade(right))
+		for dx := 2; dx < 4; dx++ {
+			img.SetRGBA(x+dx, y+2, c)
+			img.SetRGBA(x+dx, y+3, c)
+		}
+	}
+}
+
+// faceVisible checks if the face of the block at the position passed is visible. This is the case if the block on
+// that side is air, above the ceiling or not loaded at all.
+func faceVisible(pos cube.Pos, face cube.Face, ceiling int16, chunks map[world.ChunkPos]*chunk.Chunk) bool {
+	side := pos.Side(face)
+	if side.Y() > int(ceiling) {
+		return true
+	}
+	rid, ok := blockAt(chunks, side)
+	return !ok || rid == air
+}
+
+// faceShade returns the modifier that the colour of a block face is multiplied with, based on the axis of the face.
+func faceShade(face cube.Face) float64 {
+	switch face.Axis() {
+	case cube.X:
+		return 0.6
+	case cube.Z:
+		return 0.8
+	}
+	return 1
+}
+
+// shade multiplies the R, G and B values of the colour passed with the modifier passed.
+func shade(colour color.RGBA, modifier float64) color.RGBA {
+	colour.R = uint8(float64(colour.R) * modifier)
+	colour.G = uint8(float64(colour.G) * modifier)
+	colour.B = uint8(float64(colour.B) * modifier)
+	return colour
+}
+
+// blockAt returns the runtime ID of the block at the position passed. False is returned if the chunk holding the
+// position does not exist or if the position is out of the bounds of the chunk.
+func blockAt(chunks map[world.ChunkPos]*chunk.Chunk, pos cube.Pos) (uint32, bool) {
+	c, ok := chunks[world.ChunkPos{int32(pos[0] >> 4), int32(pos[2] >> 4)}]
+	if !ok || pos.OutOfBounds(c.Range()) {
+		return 0, false
+	}
+	return c.Block(uint8(pos[0]), int16(pos[1]), uint8(pos[2]), 0), true
+}
+
+// filledRange returns the lowest and highest Y value of the non-empty sub chunks in the chunk passed, with the highest
+// value clamped to the ceiling passed. False is returned if there are no non-empty sub chunks below the ceiling.
+func filledRange(ch *chunk.Chunk, ceiling int16) (minY, maxY int, ok bool) {
+	for index, sub := range ch.Sub() {
+		if sub.Empty() {
+			continue
+		}
+		y := int(ch.SubY(int16(index)))
+		if y > int(ceiling) {
+			break
+		}
+		if !ok {
+			minY, ok = y, true
+		}
+		maxY = y + 15
+	}
+	if maxY > int(ceiling) {
+		maxY = int(ceiling)
+	}
+	return minY, maxY, ok
+}
+
+// rotationSteps returns the amount of 90 degree steps to the right needed to turn from the north to the direction
+// passed.
+func rotationSteps(rot cube.Direction) int {
+	switch rot {
+	case cube.East:
+		return 1
+	case cube.South:
+		return 2
+	case cube.West:
+		return 3
+	}
+	return 0
+}
+
+// rotateColumn rotates the column or chunk position passed by 90 degrees to the right for the amount of steps needed
+// to reach the direction passed.
+func rotateColumn(x, z int, rot cube.Direction) (int, int) {
+	for i := 0; i < rotationSteps(rot); i++ {
+		x, z = -z-1, x
+	}
+	return x, z
+}
+
+// rotateLocal rotates the position within a chunk passed by 90 degrees to the right n times.
+func rotateLocal(x, z, n int) (int, int) {
+	for i := 0; i < n; i++ {
+		x, z = 15-z, x
+	}
+	return x, z
+}
+
+// IsometricImage renders all chunks in the ChunkSource passed isometrically into a single image, viewed from the
+// rotation passed. Only blocks at or below the ceiling passed are rendered. Blocks are drawn using the colours of the
+// Palette passed, which may be nil to use the colours of their materials.
+func IsometricImage(p *Palette, rot cube.Direction, ceiling int16, src ChunkSource) *image.RGBA {
+	var (
+		rendered []*isometricChunk
+		bounds   image.Rectangle
+	)
+	for _, pos := range src.Positions() {
+		chunks, unlock := lockNeighbourhood(src, pos)
+		var c *isometricChunk
+		if _, ok := chunks[pos]; ok {
+			c = renderIsometricChunk(p, rot, ceiling, pos, chunks)
+		}
+		unlock()
+		if c != nil {
+			rendered = append(rendered, c)
+			bounds = bounds.Union(c.img.Bounds())
+		}
+	}
+	sortIsometric(rendered)
+
+	img := image.NewRGBA(bounds)
+	for _, c := range rendered {
+		draw.Draw(img, c.img.Bounds(), c.img, c.img.Bounds().Min, draw.Over)
+	}
+	return img
+}
+
+// sortIsometric sorts the isometric chunks passed so that the chunks furthest away from the viewer come first.
+func sortIsometric(chunks []*isometricChunk) {
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].depth < chunks[j].depth
+	})
+}
+
+// isometricTile is an isometric chunk uploaded to an *ebiten.Image so that it may be drawn by the Renderer.
+type isometricTile struct {
+	img    *ebiten.Image
+	bounds image.Rectangle
+	depth  int32
+}
+
+// rerenderIsometric discards all isometric chunks and starts rendering them again in the background, starting with
+// the chunks closest to the center of the screen.
+func (r *Renderer) rerenderIsometric() {
+	r.renderMu.Lock()
+	r.isoGen++
+	gen, rot, ceiling, src, p := r.isoGen, r.rotation, r.ceiling(), r.src, r.palette
+	r.isoCache = make(map[world.ChunkPos]*isometricTile)
+	r.isoNeedsSorting = true
+	r.renderMu.Unlock()
+
+	center := world.ChunkPos{int32(r.pos.X()/r.scale) >> 4, int32(r.pos.Y()/r.scale) >> 4}
+	go func() {
+		positions := src.Positions()
+
+		sort.Slice(positions, func(i, j int) bool {
+			return chunkDistance(positions[i], center) < chunkDistance(positions[j], center)
+		})
+		for _, pos := range positions {
+			var c *isometricChunk
+			chunks, unlock := lockNeighbourhood(src, pos)
+			if _, ok := chunks[pos]; ok {
+				c = renderIsometricChunk(p, rot, ceiling, pos, chunks)
+			}
+			unlock()
+
+			r.renderMu.Lock()
+			if gen != r.isoGen {
+				// The isometric render was restarted, so this render is no longer relevant.
+				r.renderMu.Unlock()
+				return
+			}
+			r.storeIsometric(pos, c)
+			r.renderMu.Unlock()
+		}
+	}()
+}
+
+// storeIsometric stores an isometric chunk at the position passed. If the chunk is nil, any isometric chunk currently
+// stored at that position is removed. storeIsometric must be called while holding the render mutex.
+func (r *Renderer) storeIsometric(pos world.ChunkPos, c *isometricChunk) {
+	if r.isoCache == nil {
+		return
+	}
+	r.isoNeedsSorting = true
+	if c == nil {
+		delete(r.isoCache, pos)
+		return
+	}
+	r.isoCache[pos] = &isometricTile{img: ebiten.NewImageFromImage(c.img), bounds: c.img.Bounds(), depth: c.depth}
+}
+
+// rotate changes the rotation the isometric render is viewed from and rerenders it.
+func (r *Renderer) rotate(rot cube.Direction) {
+	r.renderMu.Lock()
+	r.rotation = rot
+	r.needsIsometric = true
+	r.renderMu.Unlock()
+}
+
+// drawIsometric draws the isometric chunks rendered so far on the screen, centered on the current position of the
+// renderer.
+func (r *Renderer) drawIsometric(screen *ebiten.Image) {
+	w, h := screen.Size()
+	scale := r.scale / 4
+
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+
+	// Find the position of the camera in isometric pixel space by projecting the world position at the center of the
+	// screen, using either the cut-plane or sea level as the Y value.
+	x, z := rotatePoint(r.pos.X()/r.scale, r.pos.Y()/r.scale, r.rotation)
+	y := float64(clampY(64, r.dimRange))
+	if r.sliced {
+		y = float64(r.sliceY)
+	}
+	cameraX, cameraY := (x-z)*2, (x+z)-y*2
+
+	if r.isoNeedsSorting {
+		r.isoSorted = r.isoSorted[:0]
+		for _, t := range r.isoCache {
+			r.isoSorted = append(r.isoSorted, t)
+		}
+		sort.Slice(r.isoSorted, func(i, j int) bool {
+			return r.isoSorted[i].depth < r.isoSorted[j].depth
+		})
+		r.isoNeedsSorting = false
+	}
+	for _, t := range r.isoSorted {
+		geo := ebiten.GeoM{}
+		geo.Translate(float64(t.bounds.Min.X)-cameraX, float64(t.bounds.Min.Y)-cameraY)
+		geo.Scale(scale, scale)
+		geo.Translate(float64(w/2), float64(h/2))
+		screen.DrawImage(t.img, &ebiten.DrawImageOptions{GeoM: geo})
+	}
+}
+
+// rotatePoint rotates the horizontal world position passed by 90 degrees to the right for the amount of steps needed
+// to reach the direction passed.
+func rotatePoint(x, z float64, rot cube.Direction) (float64, float64) {
+	for i := 0; i < rotationSteps(rot); i++ {
+		x, z = -z, x
+	}
+	return x, z
+}
+
+// chunkDistance returns the squared distance between two chunk positions.
+func chunkDistance(a, b world.ChunkPos) int64 {
+	dx, dz := int64(a.X()-b.X()), int64(a.Z()-b.Z())
+	return dx*dx + dz*dz
+}
@@ -0,0 +1,94 @@
+package worldrenderer
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"image/color"
+)
+
+// lightMode is a mode in which light levels are drawn on top of the rendered world.
+type lightMode int
+
+const (
+	// lightNone draws no light levels at all.
+	lightNone lightMode = iota
+	// lightSpawnable marks surfaces that mobs may spawn on if the block light above them is below the light threshold.
+	lightSpawnable
+	// lightSky darkens blocks based on the sky light above them.
+	lightSky
+)
+
+// String returns a name describing the light mode.
+func (m lightMode) String() string {
+	switch m {
+	case lightSpawnable:
+		return "spawnable"
+	case lightSky:
+		return "sky light"
+	}
+	return "none"
+}
+
+// darkSpotColour is the colour mixed into the colour of spawnable surfaces with a block light below the threshold.
+var darkSpotColour = color.RGBA{R: 255, A: 255}
+
+// calculateLight runs the light engine on all chunks in the map passed, which must hold the chunk at the position
+// passed and its neighbours. The light of the chunk at the position passed is correct afterwards, including light
+// spread from its neighbours. Chunks that are missing are treated as empty chunks that light spreads into, but that
+// do not spread any light themselves.
+func calculateLight(pos world.ChunkPos, chunks map[world.ChunkPos]*chunk.Chunk) {
+	r := chunks[pos].Range()
+	empty := chunk.New(air, r)
+	chunk.FillLight(empty)
+
+	for _, c := range chunks {
+		chunk.FillLight(c)
+	}
+	for chunkPos, c := range chunks {
+		// The order of the neighbours is the order expected by chunk.SpreadLight.
+		neighbours := make([]*chunk.Chunk, 0, 8)
+		for x := int32(-1); x <= 1; x++ {
+			for z := int32(-1); z <= 1; z++ {
+				if x == 0 && z == 0 {
+					continue
+				}
+				neighbour, ok := chunks[world.ChunkPos{chunkPos.X() + x, chunkPos.Z() + z}]
+				if !ok {
+					neighbour = empty
+				}
+				neighbours = append(neighbours, neighbour)
+			}
+		}
+		chunk.SpreadLight(c, neighbours)
+	}
+}
+
+// spawnable checks if mobs could spawn on top of the block at the position passed in the chunk. This is the case if
+// the block fully blocks light and the two blocks above it are air.
+func spawnable(c *chunk.Chunk, x uint8, y int16, z uint8) bool {
+	if int(y)+2 > c.Range().Max() {
+		return false
+	}
+	rid := c.Block(x, y, z, 0)
+	return rid != air && chunk.FilteringBlocks[rid] == 15 && c.Block(x, y+1, z, 0) == air && c.Block(x, y+2, z, 0) == air
+}
+
+// lightAbove returns the block light and sky light of the block above the position passed in the chunk. If the
+// position is at the top of the chunk, the block light is 0 and the sky light is 15.
+func lightAbove(c *chunk.Chunk, x uint8, y int16, z uint8) (block, sky uint8) {
+	if int(y) >= c.Range().Max() {
+		return 0, 15
+	}
+	sub := c.SubChunk(y + 1)
+	return sub.BlockLight(x, uint8((y+1)&0xf), z), sub.SkyLight(x, uint8((y+1)&0xf), z)
+}
+
+// mix mixes the two colours passed equally.
+func mix(a, b color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8((uint16(a.R) + uint16(b.R)) / 2),
+		G: uint8((uint16(a.G) + uint16(b.G)) / 2),
+		B: uint8((uint16(a.B) + uint16(b.B)) / 2),
+		A: a.A,
+	}
+}
@@ -0,0 +1,147 @@
+package worldrenderer
+
+import (
+	"fmt"
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+)
+
+// gridMode is a type of grid that may be drawn on top of the rendered world.
+type gridMode int
+
+const (
+	// gridNone draws no grid at all.
+	gridNone gridMode = iota
+	// gridChunks draws the borders of all chunks.
+	gridChunks
+	// gridRegions draws the borders of all regions, which are 32x32 chunks in size.
+	gridRegions
+)
+
+var (
+	// chunkGridColour is the colour of the lines of the chunk grid.
+	chunkGridColour = color.RGBA{A: 80}
+	// regionGridColour is the colour of the lines of the region grid.
+	regionGridColour = color.RGBA{R: 120, A: 160}
+	// slimeChunkColour is the colour drawn over slime chunks.
+	slimeChunkColour = color.RGBA{G: 90, A: 90}
+)
+
+// updateDrag pans the renderer while the left mouse button is held down.
+func (r *Renderer) updateDrag() {
+	x, y := ebiten.CursorPosition()
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		r.dragging = false
+		return
+	}
+	if r.dragging {
+		r.pos = r.pos.Sub(mgl64.Vec2{float64(x - r.dragX), float64(y - r.dragY)})
+	}
+	r.dragging, r.dragX, r.dragY = true, x, y
+}
+
+// worldPos converts a position on a screen with the width and height passed to a horizontal world position. It is the
+// inverse of screenPos.
+func (r *Renderer) worldPos(x, y float64, w, h int) (float64, float64) {
+	return (x - float64(w/2) + r.pos.X()) / r.scale, (y - float64(h/2) + r.pos.Y()) / r.scale
+}
+
+// drawGrid draws the chunk or region grid on the screen, depending on the grid mode of the renderer.
+func (r *Renderer) drawGrid(screen *ebiten.Image) {
+	size, colour := 16, chunkGridColour
+	switch r.grid {
+	case gridNone:
+		return
+	case gridRegions:
+		size, colour = 512, regionGridColour
+	}
+	w, h := screen.Size()
+	minX, minZ := r.worldPos(0, 0, w, h)
+	maxX, maxZ := r.worldPos(float64(w), float64(h), w, h)
+
+	for x := math.Floor(minX/float64(size)) * float64(size); x <= maxX; x += float64(size) {
+		screenX, _ := r.screenPos(x, 0, w, h)
+		ebitenutil.DrawLine(screen, screenX, 0, screenX, float64(h), colour)
+	}
+	for z := math.Floor(minZ/float64(size)) * float64(size); z <= maxZ; z += float64(size) {
+		_, screenZ := r.screenPos(0, z, w, h)
+		ebitenutil.DrawLine(screen, 0, screenZ, float64(w), screenZ, colour)
+	}
+}
+
+// drawSlimeChunks tints all slime chunks visible on the screen if the slime chunk overlay is enabled. Nothing is drawn
+// if chunks are too small on the screen to be told apart.
+func (r *Renderer) drawSlimeChunks(screen *ebiten.Image) {
+	size := 16 * r.scale
+	if !r.slimeChunks || size < 2 {
+		return
+	}
+	w, h := screen.Size()
+	minX, minZ := r.worldPos(0, 0, w, h)
+	maxX, maxZ := r.worldPos(float64(w), float64(h), w, h)
+	for x := int32(math.Floor(minX)) >> 4; x <= int32(math.Floor(maxX))>>4; x++ {
+		for z := int32(math.Floor(minZ)) >> 4; z <= int32(math.Floor(maxZ))>>4; z++ {
+			if world.SlimeChunk(world.ChunkPos{x, z}) {
+				screenX, screenZ := r.screenPos(float64(x<<4), float64(z<<4), w, h)
+				ebitenutil.DrawRect(screen, screenX, screenZ, size, size, slimeChunkColour)
+			}
+		}
+	}
+}
+
+// cursorBlock returns the X and Z coordinates of the block column under the cursor.
+func (r *Renderer) cursorBlock(screen *ebiten.Image) (x, z int) {
+	w, h := screen.Size()
+	cursorX, cursorY := ebiten.CursorPosition()
+	worldX, worldZ := r.worldPos(float64(cursorX), float64(cursorY), w, h)
+	return int(math.Floor(worldX)), int(math.Floor(worldZ))
+}
+
+// drawHover draws a tooltip next to the cursor with the block state, height and biome of the rendered block under the
+// cursor, using the view state of the current frame. Nothing is drawn if the column under the cursor is not loaded.
+func (r *Renderer) drawHover(screen *ebiten.Image, v viewState) {
+	x, z := r.cursorBlock(screen)
+
+	c, ok := r.source().Chunk(world.ChunkPos{int32(x >> 4), int32(z >> 4)})
+	if !ok {
+		return
+	}
+	c.Lock()
+	y := c.HighestBlockBelow(uint8(x), uint8(z), v.ceiling)
+	name, properties, _ := chunk.RuntimeIDToState(c.Block(uint8(x), y, uint8(z), 0))
+	biome := c.Biome(uint8(x), y, uint8(z))
+	c.Unlock()
+
+	biomeName, ok := world.BiomeName(biome)
+	if !ok {
+		biomeName = fmt.Sprint(biome)
+	}
+	cursorX, cursorY := ebiten.CursorPosition()
+	ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%v\nheight: %v\nbiome: %v", stateString(name, properties), y, biomeName), cursorX+16, cursorY+16)
+}
+
+// stateString formats a block state in the format name[key=value,key=value], with the properties sorted by their
+// keys. If the block state has no properties, only the name is returned.
+func stateString(name string, properties map[string]interface{}) string {
+	if len(properties) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, properties[k]))
+	}
+	return name + "[" + strings.Join(pairs, ",") + "]"
+}
@@ -0,0 +1,353 @@
+package worldrenderer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/worldsearch"
+	"github.com/muhammadmuzzammil1998/jsonc"
+	"image"
+	"image/color"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Palette holds the colours of blocks, indexed by their runtime IDs. Blocks without a colour in the palette are drawn
+// using the colour of their material. A nil *Palette holds no colours at all.
+type Palette struct {
+	colours map[uint32]color.RGBA
+}
+
+// NewPalette creates a new, empty Palette.
+func NewPalette() *Palette {
+	return &Palette{colours: make(map[uint32]color.RGBA)}
+}
+
+// colour returns the colour of the block with the runtime ID passed. If the palette holds no colour for the block, the
+// colour of its material is returned and ok is false.
+func (p *Palette) colour(rid uint32) (c color.RGBA, ok bool) {
+	if p != nil {
+		if c, ok = p.colours[rid]; ok {
+			return c, true
+		}
+	}
+	return materialColours[materials[rid]], false
+}
+
+// LoadResourcePack sets the colours of all blocks textured by the resource pack at the path passed, which may be a
+// folder or a .zip or .mcpack archive. The colour of a block is the average colour of the texture of its top face.
+// Textures of blocks such as grass and leaves, which are tinted by the biome in game, are tinted using a fixed colour.
+// Blocks cannot be matched to their textures in packs without a blocks.json or textures/terrain_texture.json, such as
+// packs that only replace some textures, so no colours are loaded from them and all blocks keep their map colour.
+func (p *Palette) LoadResourcePack(packPath string) error {
+	var fsys fs.FS
+	if info, err := os.Stat(packPath); err != nil {
+		return fmt.Errorf("error opening resource pack: %w", err)
+	} else if info.IsDir() {
+		fsys = os.DirFS(packPath)
+	} else {
+		r, err := zip.OpenReader(packPath)
+		if err != nil {
+			return fmt.Errorf("error opening resource pack archive: %w", err)
+		}
+		defer r.Close()
+		fsys = r
+	}
+	root, err := packRoot(fsys)
+	if err != nil {
+		return err
+	}
+	if fsys, err = fs.Sub(fsys, root); err != nil {
+		return fmt.Errorf("error opening resource pack: %w", err)
+	}
+
+	var blocks map[string]json.RawMessage
+	if err := readJSON(fsys, "blocks.json", &blocks); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var terrain struct {
+		TextureData map[string]struct {
+			Textures json.RawMessage `json:"textures"`
+		} `json:"texture_data"`
+	}
+	if err := readJSON(fsys, "textures/terrain_texture.json", &terrain); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Multiple blocks often share a texture, so the average colour of every texture is only computed once.
+	averages := make(map[string]color.RGBA)
+	textureColour := func(name string) (color.RGBA, bool) {
+		if c, ok := averages[name]; ok {
+			return c, c.A != 0
+		}
+		var c color.RGBA
+		if data, ok := terrain.TextureData[name]; ok {
+			if texturePath, ok := firstTexture(data.Textures); ok {
+				c = averageColour(fsys, texturePath+".png")
+			}
+		}
+		averages[name] = c
+		return c, c.A != 0
+	}
+
+	for rid := uint32(0); ; rid++ {
+		name, _, ok := chunk.RuntimeIDToState(rid)
+		if !ok {
+			break
+		}
+		shortName := strings.TrimPrefix(name, "minecraft:")
+		raw, ok := blocks[name]
+		if !ok {
+			if raw, ok = blocks[shortName]; !ok {
+				continue
+			}
+		}
+		var block struct {
+			Textures json.RawMessage `json:"textures"`
+		}
+		if err := json.Unmarshal(raw, &block); err != nil || block.Textures == nil {
+			continue
+		}
+		texture, ok := topTexture(block.Textures)
+		if !ok {
+			continue
+		}
+		c, ok := textureColour(texture)
+		if !ok {
+			continue
+		}
+		if tint, ok := tints[shortName]; ok {
+			c = multiply(c, tint)
+		}
+		p.colours[rid] = c
+	}
+	return nil
+}
+
+// LoadOverrides sets the colours of blocks specified in the JSON file at the path passed, overriding colours loaded
+// previously. The file holds an object with block queries as keys, as parsed by worldsearch.ParseQuery, and colours
+// in the format #rrggbb or #rrggbbaa as values. Comments are allowed in the file. Queries with wildcards are applied
+// first, and queries with properties last, so that more specific queries take precedence.
+func (p *Palette) LoadOverrides(overridesPath string) error {
+	data, err := os.ReadFile(overridesPath)
+	if err != nil {
+		return fmt.Errorf("error reading colour overrides: %w", err)
+	}
+	var m map[string]string
+	if err := json.Unmarshal(jsonc.ToJSON(data), &m); err != nil {
+		return fmt.Errorf("error decoding colour overrides: %w", err)
+	}
+
+	type override struct {
+		q worldsearch.Query
+		c color.RGBA
+	}
+	overrides := make([]override, 0, len(m))
+	for k, v := range m {
+		q, err := worldsearch.ParseQuery(k)
+		if err != nil {
+			return fmt.Errorf("error parsing colour overrides: %w", err)
+		}
+		c, err := parseColour(v)
+		if err != nil {
+			return fmt.Errorf("error parsing colour of %v: %w", k, err)
+		}
+		overrides = append(overrides, override{q: q, c: c})
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		a, b := overrides[i].q.String(), overrides[j].q.String()
+		if wildA, wildB := strings.Contains(a, "*"), strings.Contains(b, "*"); wildA != wildB {
+			return wildA
+		}
+		if propsA, propsB := strings.Contains(a, "["), strings.Contains(b, "["); propsA != propsB {
+			return propsB
+		}
+		return a < b
+	})
+
+	for rid := uint32(0); ; rid++ {
+		name, properties, ok := chunk.RuntimeIDToState(rid)
+		if !ok {
+			break
+		}
+		for _, o := range overrides {
+			if o.q.Matches(name, properties) {
+				p.colours[rid] = o.c
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	// grassTint, foliageTint and waterTint are the colours that the textures of blocks tinted by the biome are
+	// multiplied with. They are the colours of the plains biome.
+	grassTint   = color.RGBA{R: 145, G: 189, B: 89, A: 255}
+	foliageTint = color.RGBA{R: 119, G: 171, B: 47, A: 255}
+	waterTint   = color.RGBA{R: 68, G: 175, B: 245, A: 255}
+	// tints maps the names of blocks without namespace that are tinted by the biome in game to the colour their
+	// textures are tinted with.
+	tints = map[string]color.RGBA{
+		"grass":           grassTint,
+		"tallgrass":       grassTint,
+		"double_plant":    grassTint,
+		"short_grass":     grassTint,
+		"fern":            grassTint,
+		"large_fern":      grassTint,
+		"tall_grass":      grassTint,
+		"leaves":          foliageTint,
+		"leaves2":         foliageTint,
+		"oak_leaves":      foliageTint,
+		"jungle_leaves":   foliageTint,
+		"acacia_leaves":   foliageTint,
+		"dark_oak_leaves": foliageTint,
+		"mangrove_leaves": foliageTint,
+		"vine":            foliageTint,
+		"waterlily":       {R: 32, G: 128, B: 48, A: 255},
+		"water":           waterTint,
+		"flowing_water":   waterTint,
+	}
+)
+
+// packRoot returns the folder of the file system passed that holds the manifest.json of the resource pack. This is
+// the root of the file system, unless the pack was archived together with its folder.
+func packRoot(fsys fs.FS) (string, error) {
+	if _, err := fs.Stat(fsys, "manifest.json"); err == nil {
+		return ".", nil
+	}
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return "", fmt.Errorf("error reading resource pack: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(fsys, path.Join(entry.Name(), "manifest.json")); err == nil {
+			return entry.Name(), nil
+		}
+	}
+	return "", fmt.Errorf("resource pack has no manifest.json")
+}
+
+// readJSON reads the JSON file with the name passed from the file system and decodes it into v. Comments are allowed
+// in the file, as they are in resource packs.
+func readJSON(fsys fs.FS, name string, v interface{}) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("error reading %v: %w", name, err)
+	}
+	if err := json.Unmarshal(jsonc.ToJSON(data), v); err != nil {
+		return fmt.Errorf("error decoding %v: %w", name, err)
+	}
+	return nil
+}
+
+// topTexture returns the name of the texture on the top face of a block from the textures field of its entry in
+// blocks.json. The field is either the name of a texture used for all faces, or an object with a texture per face.
+func topTexture(raw json.RawMessage) (string, bool) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name, true
+	}
+	var faces map[string]string
+	if err := json.Unmarshal(raw, &faces); err != nil {
+		return "", false
+	}
+	for _, face := range []string{"up", "side", "north"} {
+		if name, ok := faces[face]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// firstTexture returns the path of the first texture from the textures field of an entry in terrain_texture.json. The
+// field is either a path, an object with a path, or a list of either of them, one for every variation of the texture.
+func firstTexture(raw json.RawMessage) (string, bool) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		if len(list) == 0 {
+			return "", false
+		}
+		raw = list[0]
+	}
+	var texturePath string
+	if err := json.Unmarshal(raw, &texturePath); err == nil {
+		return texturePath, true
+	}
+	var obj struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil || obj.Path == "" {
+		return "", false
+	}
+	return obj.Path, true
+}
+
+// averageColour returns the average colour of all pixels that are not fully transparent in the image with the name
+// passed. A fully transparent colour is returned if the image could not be read or has no visible pixels.
+func averageColour(fsys fs.FS, name string) color.RGBA {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return color.RGBA{}
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return color.RGBA{}
+	}
+
+	var r, g, b, n uint64
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A == 0 {
+				continue
+			}
+			r, g, b, n = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), n+1
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255}
+}
+
+// multiply multiplies the colour passed with a tint, as done for textures tinted by the biome.
+func multiply(c, tint color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8(uint16(c.R) * uint16(tint.R) / 255),
+		G: uint8(uint16(c.G) * uint16(tint.G) / 255),
+		B: uint8(uint16(c.B) * uint16(tint.B) / 255),
+		A: c.A,
+	}
+}
+
+// parseColour parses a colour in the format #rrggbb or #rrggbbaa.
+func parseColour(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 6 {
+		s += "ff"
+	}
+	if len(s) != 8 {
+		return color.RGBA{}, fmt.Errorf("colour %q must be in the format #rrggbb or #rrggbbaa", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("colour %q is not hexadecimal", s)
+	}
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
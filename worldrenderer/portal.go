@@ -0,0 +1,141 @@
+package worldrenderer
+
+import (
+	"fmt"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/justtaldevelops/worldcompute/worldsearch"
+	"image/color"
+	"math"
+)
+
+var (
+	// portalQuery is the query matching the blocks of nether portals.
+	portalQuery, _ = worldsearch.ParseQuery("minecraft:portal")
+	// portalColour is the colour of the markers drawn on portals in the dimension viewed, and linkedPortalColour the
+	// colour of the markers drawn on the linked positions of portals in the linked dimension.
+	portalColour       = color.RGBA{R: 140, G: 40, B: 220, A: 255}
+	linkedPortalColour = color.RGBA{R: 255, G: 170, B: 40, A: 255}
+)
+
+// SetNetherScale sets the amount of blocks in the overworld that a single block in the nether corresponds to. It is
+// used to link positions in the overworld and the nether.
+func (r *Renderer) SetNetherScale(scale float64) {
+	if scale <= 0 {
+		return
+	}
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	r.netherScale = scale
+}
+
+// dimensions returns the dimension that the local player is in and the dimension currently viewed.
+func (r *Renderer) dimensions() (dim, viewDim world.Dimension) {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	return r.dim, r.viewDim
+}
+
+// linkedDimension returns the dimension linked to the dimension passed through nether portals. False is returned if
+// the dimension is not linked to another dimension.
+func linkedDimension(dim world.Dimension) (world.Dimension, bool) {
+	switch dim {
+	case world.Overworld:
+		return world.Nether, true
+	case world.Nether:
+		return world.Overworld, true
+	}
+	return nil, false
+}
+
+// linkedScale returns the factor that coordinates in the dimension passed are multiplied by to obtain the
+// corresponding coordinates in the linked dimension.
+func (r *Renderer) linkedScale(dim world.Dimension) float64 {
+	if dim == world.Nether {
+		return r.netherScale
+	}
+	return 1 / r.netherScale
+}
+
+// storePortals stores the positions of the portal blocks found in the chunk at a position in the dimension passed,
+// replacing any portals previously found there. storePortals must be called while holding renderMu.
+func (r *Renderer) storePortals(dim world.Dimension, pos world.ChunkPos, portals []cube.Pos) {
+	m, ok := r.portals[dim]
+	if !ok {
+		if len(portals) == 0 {
+			return
+		}
+		m = make(map[world.ChunkPos][]cube.Pos)
+		r.portals[dim] = m
+	}
+	if len(portals) == 0 {
+		delete(m, pos)
+		return
+	}
+	m[pos] = portals
+}
+
+// jump switches the view to the dimension linked to the dimension viewed, keeping the linked position of the
+// position viewed at the centre of the screen. Nothing happens if the dimension viewed is not linked.
+func (r *Renderer) jump() {
+	r.renderMu.Lock()
+	linked, ok := linkedDimension(r.viewDim)
+	scale := r.linkedScale(r.viewDim)
+	r.renderMu.Unlock()
+	if !ok {
+		return
+	}
+	r.pos = r.pos.Mul(scale)
+	r.view(linked)
+}
+
+// linkedPosition returns a line for the HUD holding the position in the linked dimension corresponding to the block
+// position passed in the dimension viewed. An empty string is returned if the dimension viewed is not linked.
+func (r *Renderer) linkedPosition(x, z int) string {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	linked, ok := linkedDimension(r.viewDim)
+	if !ok {
+		return ""
+	}
+	scale := r.linkedScale(r.viewDim)
+	return fmt.Sprintf("%v: %v, %v", linked, math.Floor(float64(x)*scale), math.Floor(float64(z)*scale))
+}
+
+// drawPortals draws a marker on every portal block of the dimension viewed, and on the linked positions of every
+// portal block in the linked dimension. Portals of a single column are only drawn once.
+func (r *Renderer) drawPortals(screen *ebiten.Image) {
+	w, h := screen.Size()
+	size := r.scale
+	if size < 3 {
+		size = 3
+	}
+
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	draw := func(portals map[world.ChunkPos][]cube.Pos, scale float64, colour color.Color) {
+		drawn := make(map[[2]float64]struct{})
+		for _, positions := range portals {
+			for _, pos := range positions {
+				x, z := math.Floor(float64(pos.X())*scale), math.Floor(float64(pos.Z())*scale)
+				if _, ok := drawn[[2]float64{x, z}]; ok {
+					continue
+				}
+				drawn[[2]float64{x, z}] = struct{}{}
+
+				sx, sy := r.screenPos(x+0.5, z+0.5, w, h)
+				if sx+size < 0 || sy+size < 0 || sx-size > float64(w) || sy-size > float64(h) {
+					continue
+				}
+				ebitenutil.DrawRect(screen, sx-size/2-1, sy-size/2-1, size+2, size+2, outlineColour)
+				ebitenutil.DrawRect(screen, sx-size/2, sy-size/2, size, size, colour)
+			}
+		}
+	}
+	if linked, ok := linkedDimension(r.viewDim); ok {
+		draw(r.portals[linked], r.linkedScale(linked), linkedPortalColour)
+	}
+	draw(r.portals[r.viewDim], 1, portalColour)
+}
@@ -1,37 +1,140 @@
 package worldrenderer
 
 import (
+	"fmt"
 	"github.com/go-gl/mathgl/mgl64"
 	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
 	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/justtaldevelops/worldcompute/worldsearch"
+	"image/png"
+	"io"
+	"math"
+	"strings"
 	"sync"
+	"time"
+)
+
+const (
+	// minScale and maxScale are the lowest and highest scales the renderer may be zoomed to. A scale of 1 means that
+	// every block is drawn as a single pixel.
+	minScale, maxScale = 1.0 / 32, 64
+	// zoomStep is the factor the scale is multiplied or divided by for every scroll step.
+	zoomStep = 1.25
 )
 
 // Renderer implements the ebiten.Game interface.
 type Renderer struct {
-	scale int
+	scale float64
 	drift float64
 	pos   mgl64.Vec2
 
-	needsRerender bool
-	shouldCenter  bool
-	centerPos     mgl64.Vec2
+	shouldCenter bool
+	centerPos    mgl64.Vec2
+
+	// dragging is true while the renderer is being panned with the mouse. dragX and dragY hold the cursor position of
+	// the previous frame.
+	dragging     bool
+	dragX, dragY int
+	grid         gridMode
+	// slimeChunks is true if slime chunks are tinted.
+	slimeChunks bool
+
+	// sliced is true if the renderer is in cut-plane mode, in which case only blocks at or below sliceY are rendered.
+	sliced bool
+	sliceY int
+	// dimRange is the cube.Range of the dimension currently rendered. The sliceY is always clamped to this range.
+	dimRange cube.Range
+
+	// light is the light mode the world is rendered in. In the lightSpawnable mode, surfaces with a block light below
+	// lightThreshold are marked. The threshold defaults to 1, as hostile mobs only spawn at a block light of 0.
+	light          lightMode
+	lightThreshold uint8
+
+	// isometric is true if the world is rendered isometrically rather than from the top down. The isometric render is
+	// viewed from the rotation. Isometric chunks are rendered in the background, with isoGen being incremented each
+	// time the isometric render is restarted to stop outdated renders.
+	isometric       bool
+	needsIsometric  bool
+	rotation        cube.Direction
+	isoGen          int
+	isoCache        map[world.ChunkPos]*isometricTile
+	isoSorted       []*isometricTile
+	isoNeedsSorting bool
+
+	// entityMu protects the markers of the local player, other players and entities, and the highlighted blocks.
+	// Entities other than players are only drawn if showEntities is true.
+	entityMu     sync.Mutex
+	local        *marker
+	markers      map[uint64]*marker
+	showEntities bool
+	highlights   []cube.Pos
+
+	// dim is the dimension the local player is in, and viewDim the dimension currently rendered. Markers are only
+	// drawn and the renderer only follows the local player if both are the same. src is the ChunkSource of viewDim,
+	// taken from the sources of all dimensions.
+	dim, viewDim world.Dimension
+	src          ChunkSource
+	sources      map[world.Dimension]ChunkSource
+	// netherScale is the amount of blocks in the overworld that a single block in the nether corresponds to. portals
+	// holds the positions of all portal blocks found in the rendered chunks of every dimension.
+	netherScale float64
+	portals     map[world.Dimension]map[world.ChunkPos][]cube.Pos
+
+	// palette is the Palette used to colour blocks. If nil, blocks are coloured using their material.
+	palette *Palette
 
-	chunkMu *sync.Mutex
-	chunks  map[world.ChunkPos]*chunk.Chunk
+	// captureMode is the capture requested to be taken when the renderer is next drawn. timeLapse is the time-lapse
+	// currently being recorded, or nil if none is recorded, and timeLapseInterval the interval between its frames.
+	captureMode       captureMode
+	timeLapse         *timeLapse
+	timeLapseInterval time.Duration
+	// status is a message about a capture shown in the HUD, set at statusTime.
+	status     string
+	statusTime time.Time
 
-	renderMu    *sync.Mutex
-	renderCache map[world.ChunkPos]*ebiten.Image
+	// renderMu protects the tiles and the queue of dirty chunks. Chunks are rendered at one pixel per block into tiles,
+	// which are scaled when drawn. Only dirty chunks are rendered again, by workers in the background. Chunks being
+	// rendered are held in inFlight, and finished renders in results until they are swapped into the tiles.
+	renderMu   *sync.Mutex
+	renderCond *sync.Cond
+	tiles      map[tilePos]*tile
+	dirty      map[world.ChunkPos]struct{}
+	inFlight   map[world.ChunkPos]struct{}
+	results    map[world.ChunkPos]renderResult
 }
 
-// NewRendererDirect creates a new renderer with the given chunks.
-func NewRendererDirect(scale int, drift float64, centerPos mgl64.Vec2, chunkMu *sync.Mutex, chunks map[world.ChunkPos]*chunk.Chunk) *Renderer {
-	r := &Renderer{scale: scale, drift: drift, renderMu: new(sync.Mutex), shouldCenter: true}
-	r.renderCache = renderWorld(r.scale, chunkMu, chunks)
+// NewRenderer creates a new renderer rendering the chunks of the ChunkSource passed, which holds the chunks of the
+// dimension passed. Chunks are rerendered every time the ChunkSource reports that they changed. Sources for other
+// dimensions may be added using SetSource.
+func NewRenderer(scale float64, drift float64, centerPos mgl64.Vec2, dim world.Dimension, src ChunkSource) *Renderer {
+	r := &Renderer{
+		scale:             scale,
+		drift:             drift,
+		renderMu:          new(sync.Mutex),
+		shouldCenter:      true,
+		lightThreshold:    1,
+		dim:               dim,
+		viewDim:           dim,
+		src:               src,
+		sources:           make(map[world.Dimension]ChunkSource),
+		netherScale:       8,
+		timeLapseInterval: time.Second * 5,
+		portals:           make(map[world.Dimension]map[world.ChunkPos][]cube.Pos),
+		markers:           make(map[uint64]*marker),
+		tiles:             make(map[tilePos]*tile),
+		dirty:             make(map[world.ChunkPos]struct{}),
+		inFlight:          make(map[world.ChunkPos]struct{}),
+		results:           make(map[world.ChunkPos]renderResult),
+	}
+	r.renderCond = sync.NewCond(r.renderMu)
+	r.dimRange = dim.Range()
+	r.sliceY = r.dimRange.Max()
 	r.centerPos = centerPos
-	r.chunkMu = chunkMu
-	r.chunks = chunks
+	r.startWorkers()
+	r.SetSource(dim, src)
 	return r
 }
 
@@ -49,24 +152,101 @@ func (r *Renderer) Update() error {
 	if ebiten.IsKeyPressed(ebiten.KeyRight) {
 		r.pos = r.pos.Add(mgl64.Vec2{r.drift, 0})
 	}
+	r.updateDrag()
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyG) {
+		r.grid = (r.grid + 1) % 3
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+		r.renderMu.Lock()
+		r.sliced = !r.sliced
+		r.renderMu.Unlock()
+		r.Rerender()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyF12) {
+		r.captureMode = captureView
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			r.captureMode = captureArea
+		}
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyT) {
+		r.toggleTimeLapse()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyP) {
+		r.jump()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyS) {
+		r.slimeChunks = !r.slimeChunks
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyL) {
+		r.renderMu.Lock()
+		r.light = (r.light + 1) % 3
+		r.renderMu.Unlock()
+		r.Rerender()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyN) {
+		r.entityMu.Lock()
+		r.showEntities = !r.showEntities
+		r.entityMu.Unlock()
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyI) {
+		r.renderMu.Lock()
+		r.isometric = !r.isometric
+		r.needsIsometric = r.isometric
+		r.renderMu.Unlock()
+	}
+
+	v := r.viewState()
+	if v.light == lightSpawnable {
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketLeft) {
+			r.moveLightThreshold(-1)
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyBracketRight) {
+			r.moveLightThreshold(1)
+		}
+	}
+	if v.isometric {
+		if inpututil.IsKeyJustPressed(ebiten.KeyQ) {
+			r.rotate(v.rotation.RotateLeft())
+		}
+		if inpututil.IsKeyJustPressed(ebiten.KeyE) {
+			r.rotate(v.rotation.RotateRight())
+		}
+	}
 
 	oldScale := r.scale
 	_, yOff := ebiten.Wheel()
-	if yOff > 0 {
-		r.scale++
+	if v.sliced && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		// Scrolling while holding shift moves the cut-plane rather than changing the scale.
+		if yOff > 0 {
+			r.moveSlice(1)
+		} else if yOff < 0 {
+			r.moveSlice(-1)
+		}
+	} else if yOff > 0 {
+		r.scale = math.Min(r.scale*zoomStep, maxScale)
 	} else if yOff < 0 {
-		r.scale--
+		r.scale = math.Max(r.scale/zoomStep, minScale)
 	}
-	if r.scale <= 0 {
-		r.scale = 1
+	if v.sliced {
+		if keyRepeated(ebiten.KeyPageUp) {
+			r.moveSlice(1)
+		}
+		if keyRepeated(ebiten.KeyPageDown) {
+			r.moveSlice(-1)
+		}
 	}
-	if oldScale != r.scale || len(r.renderCache) != len(r.chunks) {
-		r.Rerender()
-		r.pos = r.pos.Mul(float64(r.scale) / (float64(oldScale)))
+	if oldScale != r.scale {
+		r.pos = r.pos.Mul(r.scale / oldScale)
 	}
-	if r.needsRerender {
-		r.renderCache = renderWorld(r.scale, r.chunkMu, r.chunks)
-		r.needsRerender = false
+	r.applyResults()
+
+	r.renderMu.Lock()
+	needsIsometric := r.needsIsometric
+	r.needsIsometric = false
+	r.renderMu.Unlock()
+	if needsIsometric {
+		r.rerenderIsometric()
 	}
 	return nil
 }
@@ -74,29 +254,102 @@ func (r *Renderer) Update() error {
 // Draw draws the screen.
 func (r *Renderer) Draw(screen *ebiten.Image) {
 	screen.Fill(materialColours[0])
+	if dim, viewDim := r.dimensions(); r.shouldCenter && dim == viewDim {
+		r.pos = r.centerPos.Mul(r.scale)
+		r.shouldCenter = false
+	}
+	v := r.viewState()
+	if v.isometric {
+		r.drawIsometric(screen)
+		r.capture(screen)
+		r.drawHUD(screen, v)
+		return
+	}
 
+	r.drawTiles(screen)
+
+	r.drawSlimeChunks(screen)
+	r.drawGrid(screen)
+	r.drawPortals(screen)
+	r.drawHighlights(screen)
+	r.drawMarkers(screen)
+	r.capture(screen)
+	r.drawHUD(screen, v)
+	r.drawHover(screen, v)
+}
+
+// screenPos converts a horizontal world position to a position on a screen with the width and height passed.
+func (r *Renderer) screenPos(x, z float64, w, h int) (float64, float64) {
+	return float64(w/2) + x*r.scale - r.pos.X(), float64(h/2) + z*r.scale - r.pos.Y()
+}
+
+// drawTiles draws all tiles visible on the screen. The level of detail of every tile is picked so that the tile is
+// never drawn at less than half its size.
+func (r *Renderer) drawTiles(screen *ebiten.Image) {
 	w, h := screen.Size()
-	chunkScale := float64(r.scale) * 16
-	centerX, centerZ := float64(w/2), float64(h/2)
-	if r.shouldCenter {
-		r.pos = r.centerPos.Mul(float64(r.scale))
-		r.shouldCenter = false
+	level := 0
+	if r.scale < 1 {
+		level = int(math.Floor(math.Log2(1 / r.scale)))
+		if level >= mipLevels {
+			level = mipLevels - 1
+		}
 	}
+	scale := r.scale * float64(int(1)<<level)
+	size := tileSize * r.scale
 
 	r.renderMu.Lock()
 	defer r.renderMu.Unlock()
-	for pos, ch := range r.renderCache {
-		chunkW, chunkH := ch.Bounds().Dx(), ch.Bounds().Dy()
-		offsetX, offsetZ := float64(chunkW/2)+r.pos.X(), float64(chunkH/2)+r.pos.Y()
-
-		chunkX, chunkZ := centerX+(float64(pos.X())*chunkScale), centerZ+(float64(pos.Z())*chunkScale)
-
+	for pos, t := range r.tiles {
+		x, y := r.screenPos(float64(pos[0])*tileSize, float64(pos[1])*tileSize, w, h)
+		if x+size < 0 || y+size < 0 || x > float64(w) || y > float64(h) || t.mips[level] == nil {
+			continue
+		}
 		geo := ebiten.GeoM{}
-		geo.Translate(chunkX-offsetX, chunkZ-offsetZ)
-		screen.DrawImage(ch, &ebiten.DrawImageOptions{GeoM: geo})
+		geo.Scale(scale, scale)
+		geo.Translate(x, y)
+		screen.DrawImage(t.mips[level], &ebiten.DrawImageOptions{GeoM: geo})
 	}
 }
 
+// drawHUD draws the on-screen information of the renderer, using the view state of the current frame.
+func (r *Renderer) drawHUD(screen *ebiten.Image, v viewState) {
+	var lines []string
+	if v.isometric {
+		lines = append(lines, fmt.Sprintf("isometric: facing %v", v.rotation))
+	} else {
+		x, z := r.cursorBlock(screen)
+		lines = append(lines,
+			fmt.Sprintf("cursor: %v, %v", x, z),
+			fmt.Sprintf("chunk: %v, %v (region %v, %v)", x>>4, z>>4, x>>9, z>>9),
+		)
+		if linked := r.linkedPosition(x, z); linked != "" {
+			lines = append(lines, linked)
+		}
+		if r.slimeChunks && world.SlimeChunk(world.ChunkPos{int32(x >> 4), int32(z >> 4)}) {
+			lines = append(lines, "slime chunk")
+		}
+	}
+	if dim, viewDim := r.dimensions(); dim != viewDim {
+		lines = append(lines, fmt.Sprintf("viewing: %v", viewDim))
+	}
+	if v.sliced {
+		lines = append(lines, fmt.Sprintf("cut-plane: Y <= %v", v.sliceY))
+	}
+	switch v.light {
+	case lightSpawnable:
+		lines = append(lines, fmt.Sprintf("light: %v (block light < %v)", v.light, v.lightThreshold))
+	case lightSky:
+		lines = append(lines, fmt.Sprintf("light: %v", v.light))
+	}
+	if r.timeLapse != nil {
+		lines = append(lines, fmt.Sprintf("time-lapse: recording every %v", r.timeLapseInterval))
+	}
+	if status := r.currentStatus(); status != "" {
+		lines = append(lines, status)
+	}
+	ebitenutil.DebugPrint(screen, strings.Join(lines, "\n"))
+}
+
 // Layout takes the outside size (e.g., the window size) and returns the (logical) screen size.
 func (r *Renderer) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
 	return outsideWidth, outsideHeight
@@ -104,30 +357,42 @@ func (r *Renderer) Layout(outsideWidth, outsideHeight int) (screenWidth, screenH
 
 // Rerender rerenders the world.
 func (r *Renderer) Rerender() {
-	r.needsRerender = true
+	r.markAllDirty()
+	r.renderMu.Lock()
+	r.needsIsometric = r.isometric
+	r.renderMu.Unlock()
 }
 
-// RerenderChunk rerenders the chunk at the given position.
+// RerenderChunk queues the chunk at the given position and its neighbours to be rerendered in the background. The
+// neighbours are rerendered too, as their shading depends on the chunk. Queueing a chunk that is already queued has no
+// effect.
 func (r *Renderer) RerenderChunk(pos world.ChunkPos) {
-	r.chunkMu.Lock()
 	r.renderMu.Lock()
-	defer r.chunkMu.Unlock()
 	defer r.renderMu.Unlock()
 
-	renderPositions := []world.ChunkPos{
-		{pos.X(), pos.Z() + 1},
-		{pos.X(), pos.Z() - 1},
-		{pos.X() + 1, pos.Z()},
-		{pos.X() - 1, pos.Z()},
-		pos,
-	}
-	for _, renderPos := range renderPositions {
-		if _, ok := r.chunks[renderPos]; !ok {
-			// Chunk doesn't exist, so we couldn't possibly render it.
-			delete(r.renderCache, renderPos)
-			continue
+	r.markDirty(pos)
+	r.markDirty(world.ChunkPos{pos.X(), pos.Z() + 1})
+	r.markDirty(world.ChunkPos{pos.X(), pos.Z() - 1})
+	r.markDirty(world.ChunkPos{pos.X() + 1, pos.Z()})
+	r.markDirty(world.ChunkPos{pos.X() - 1, pos.Z()})
+}
+
+// markAllDirty marks every chunk that currently exists or is currently rendered as dirty, so that all of them are
+// rerendered or removed.
+func (r *Renderer) markAllDirty() {
+	positions := r.source().Positions()
+
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	for _, pos := range positions {
+		r.markDirty(pos)
+	}
+	for tp, t := range r.tiles {
+		for i, rendered := range t.rendered {
+			if rendered {
+				r.markDirty(world.ChunkPos{tp[0]*regionChunks + int32(i&(regionChunks-1)), tp[1]*regionChunks + int32(i/regionChunks)})
+			}
 		}
-		r.renderCache[renderPos] = renderChunk(r.scale, renderPos, r.chunks)
 	}
 }
 
@@ -136,3 +401,203 @@ func (r *Renderer) Recenter(pos mgl64.Vec2) {
 	r.centerPos = pos
 	r.shouldCenter = true
 }
+
+// ExportIsometric renders all chunks isometrically, using the current rotation and cut-plane of the renderer, and
+// writes the result to the io.Writer passed as a PNG.
+func (r *Renderer) ExportIsometric(w io.Writer) error {
+	r.renderMu.Lock()
+	rot, ceiling, src, p := r.rotation, r.ceiling(), r.src, r.palette
+	r.renderMu.Unlock()
+
+	return png.Encode(w, IsometricImage(p, rot, ceiling, src))
+}
+
+// SetPalette sets the Palette used to colour blocks and rerenders the world. Passing nil colours all blocks using the
+// colour of their material.
+func (r *Renderer) SetPalette(p *Palette) {
+	r.renderMu.Lock()
+	r.palette = p
+	r.renderMu.Unlock()
+
+	r.Rerender()
+}
+
+// SetSource sets the ChunkSource holding the chunks of the dimension passed. If the dimension is currently viewed,
+// the world is rerendered using the new source.
+func (r *Renderer) SetSource(dim world.Dimension, src ChunkSource) {
+	r.renderMu.Lock()
+	r.sources[dim] = src
+	viewed := r.viewDim == dim
+	if viewed {
+		r.src = src
+	}
+	r.renderMu.Unlock()
+
+	// Chunks of the dimension viewed are searched for portals when they are rendered. Chunks of other dimensions are
+	// searched directly, so that their portals may be linked to those of the dimension viewed.
+	var searchMu sync.Mutex
+	portals := worldsearch.NewSearcher(portalQuery)
+	src.Listen(func(pos world.ChunkPos) {
+		r.renderMu.Lock()
+		current := r.src == src
+		r.renderMu.Unlock()
+		if current {
+			r.RerenderChunk(pos)
+			return
+		}
+		var found []cube.Pos
+		if c, ok := src.Chunk(pos); ok {
+			searchMu.Lock()
+			c.Lock()
+			found = portals.SearchChunk(pos, c)
+			c.Unlock()
+			searchMu.Unlock()
+		}
+		r.renderMu.Lock()
+		r.storePortals(dim, pos, found)
+		r.renderMu.Unlock()
+	})
+	if _, ok := src.(*ProviderSource); ok {
+		// The chunks of a saved world never change, so listeners are never called. Instead, all of its chunks are
+		// searched for portals once, in the background as a world may hold many chunks.
+		go r.searchPortals(dim, src)
+	}
+	if viewed {
+		r.Rerender()
+	}
+}
+
+// searchPortals searches all chunks of the ChunkSource of the dimension passed for portals and stores them.
+func (r *Renderer) searchPortals(dim world.Dimension, src ChunkSource) {
+	portals := worldsearch.NewSearcher(portalQuery)
+	for _, pos := range src.Positions() {
+		c, ok := src.Chunk(pos)
+		if !ok {
+			continue
+		}
+		c.Lock()
+		found := portals.SearchChunk(pos, c)
+		c.Unlock()
+
+		r.renderMu.Lock()
+		r.storePortals(dim, pos, found)
+		r.renderMu.Unlock()
+	}
+}
+
+// SetDimension changes the dimension that the local player is in and renders it. The cut-plane is clamped to the
+// range of the dimension.
+func (r *Renderer) SetDimension(dim world.Dimension) {
+	r.renderMu.Lock()
+	r.dim = dim
+	r.renderMu.Unlock()
+
+	r.view(dim)
+}
+
+// view changes the dimension rendered by the renderer, without changing the dimension of the local player. If no
+// ChunkSource was set for the dimension, an empty world is rendered.
+func (r *Renderer) view(dim world.Dimension) {
+	r.renderMu.Lock()
+	r.viewDim = dim
+	src, ok := r.sources[dim]
+	if !ok {
+		src = NewCacheSource()
+	}
+	r.src = src
+	r.dimRange = dim.Range()
+	r.sliceY = clampY(r.sliceY, r.dimRange)
+	r.renderMu.Unlock()
+
+	r.Rerender()
+}
+
+// source returns the ChunkSource of the dimension currently rendered.
+func (r *Renderer) source() ChunkSource {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	return r.src
+}
+
+// moveSlice moves the cut-plane up or down by the delta passed and rerenders the world if it changed.
+func (r *Renderer) moveSlice(delta int) {
+	r.renderMu.Lock()
+	oldY := r.sliceY
+	r.sliceY = clampY(r.sliceY+delta, r.dimRange)
+	changed := oldY != r.sliceY
+	r.renderMu.Unlock()
+
+	if changed {
+		r.Rerender()
+	}
+}
+
+// moveLightThreshold moves the light threshold up or down by the delta passed and rerenders the world if it changed.
+// The threshold is kept between 1 and 15.
+func (r *Renderer) moveLightThreshold(delta int) {
+	r.renderMu.Lock()
+	old := r.lightThreshold
+	if threshold := int(r.lightThreshold) + delta; threshold >= 1 && threshold <= 15 {
+		r.lightThreshold = uint8(threshold)
+	}
+	changed := old != r.lightThreshold
+	r.renderMu.Unlock()
+
+	if changed {
+		r.Rerender()
+	}
+}
+
+// viewState is a snapshot of the settings of the renderer that are protected by renderMu and that are needed to draw a
+// frame.
+type viewState struct {
+	sliced         bool
+	sliceY         int
+	ceiling        int16
+	light          lightMode
+	lightThreshold uint8
+	isometric      bool
+	rotation       cube.Direction
+}
+
+// viewState returns a snapshot of the view settings of the renderer, so that a frame is drawn using consistent
+// settings without holding renderMu.
+func (r *Renderer) viewState() viewState {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	return viewState{
+		sliced:         r.sliced,
+		sliceY:         r.sliceY,
+		ceiling:        r.ceiling(),
+		light:          r.light,
+		lightThreshold: r.lightThreshold,
+		isometric:      r.isometric,
+		rotation:       r.rotation,
+	}
+}
+
+// ceiling returns the highest Y value that should be rendered. If the renderer is not in cut-plane mode, this is the
+// maximum Y value of the dimension. ceiling must be called while holding renderMu.
+func (r *Renderer) ceiling() int16 {
+	if r.sliced {
+		return int16(r.sliceY)
+	}
+	return int16(r.dimRange.Max())
+}
+
+// clampY clamps the Y value passed to the cube.Range passed.
+func clampY(y int, rng cube.Range) int {
+	if y > rng.Max() {
+		return rng.Max()
+	}
+	if y < rng.Min() {
+		return rng.Min()
+	}
+	return y
+}
+
+// keyRepeated returns true if the key passed was just pressed, or if it has been held down long enough to repeat.
+func keyRepeated(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d > 20 && d%3 == 0)
+}
@@ -0,0 +1,406 @@
+package worldrenderer
+
+import (
+	"container/list"
+	"fmt"
+	"github.com/go-gl/mathgl/mgl64"
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/mcdb"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"math"
+	"sync"
+)
+
+// ChunkSource is a source of chunks that may be rendered by a Renderer. Chunks returned by a ChunkSource must be
+// locked using their embedded mutex while they are read or modified.
+type ChunkSource interface {
+	// Chunk returns the chunk at the position passed. False is returned if the source has no chunk at the position.
+	Chunk(pos world.ChunkPos) (*chunk.Chunk, bool)
+	// Positions returns the positions of all chunks in the source.
+	Positions() []world.ChunkPos
+	// Listen adds a function that is called with the position of a chunk every time it is added, changed or removed.
+	Listen(f func(pos world.ChunkPos))
+}
+
+// CacheSource is a ChunkSource holding chunks in memory, such as the chunks received from a server, together with
+// the block entities and entities in them.
+type CacheSource struct {
+	mu            sync.Mutex
+	chunks        map[world.ChunkPos]*chunk.Chunk
+	blockEntities map[world.ChunkPos]map[cube.Pos]map[string]interface{}
+	// entities holds the NBT data of the entities added to the cache, keyed by their runtime ID.
+	entities  map[uint64]map[string]interface{}
+	received  map[world.ChunkPos]receivedParts
+	listeners []func(pos world.ChunkPos)
+}
+
+// receivedParts holds the parts of a chunk that were received.
+type receivedParts struct {
+	// subs is a bit mask of the indices of the sub chunks received.
+	subs uint64
+	// biomes is true if the biomes of the chunk were received.
+	biomes bool
+}
+
+// NewCacheSource creates a new, empty CacheSource.
+func NewCacheSource() *CacheSource {
+	return &CacheSource{
+		chunks:        make(map[world.ChunkPos]*chunk.Chunk),
+		blockEntities: make(map[world.ChunkPos]map[cube.Pos]map[string]interface{}),
+		entities:      make(map[uint64]map[string]interface{}),
+		received:      make(map[world.ChunkPos]receivedParts),
+	}
+}
+
+// Chunk returns the chunk stored at the position passed.
+func (s *CacheSource) Chunk(pos world.ChunkPos) (*chunk.Chunk, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.chunks[pos]
+	return c, ok
+}
+
+// Positions returns the positions of all chunks currently stored.
+func (s *CacheSource) Positions() []world.ChunkPos {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	positions := make([]world.ChunkPos, 0, len(s.chunks))
+	for pos := range s.chunks {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// Listen adds a function called every time a chunk is stored, modified or cleared.
+func (s *CacheSource) Listen(f func(pos world.ChunkPos)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, f)
+}
+
+// Store stores the chunk passed at a position, replacing any chunk that was previously stored there.
+func (s *CacheSource) Store(pos world.ChunkPos, c *chunk.Chunk) {
+	s.mu.Lock()
+	s.chunks[pos] = c
+	s.mu.Unlock()
+	s.notify(pos)
+}
+
+// LoadOrStore returns the chunk stored at a position if one exists. Otherwise, it stores and returns the chunk passed.
+// The loaded result is true if the chunk was loaded, and false if it was stored.
+func (s *CacheSource) LoadOrStore(pos world.ChunkPos, c *chunk.Chunk) (actual *chunk.Chunk, loaded bool) {
+	s.mu.Lock()
+	if existing, ok := s.chunks[pos]; ok {
+		s.mu.Unlock()
+		return existing, true
+	}
+	s.chunks[pos] = c
+	s.mu.Unlock()
+	s.notify(pos)
+	return c, false
+}
+
+// Modify calls the function passed with the chunk at a position while holding the lock of the chunk. False is
+// returned and the function is not called if no chunk exists at the position.
+func (s *CacheSource) Modify(pos world.ChunkPos, f func(c *chunk.Chunk)) bool {
+	c, ok := s.Chunk(pos)
+	if !ok {
+		return false
+	}
+	c.Lock()
+	f(c)
+	c.Unlock()
+	s.notify(pos)
+	return true
+}
+
+// MarkReceived records that the sub chunks with the indices set in the bit mask passed, and the biomes if biomes is
+// true, of the chunk at a position were received. Chunks are often only partially received from a server, and only
+// the parts received should replace those of a chunk that was saved before.
+func (s *CacheSource) MarkReceived(pos world.ChunkPos, subs uint64, biomes bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.received[pos]
+	r.subs |= subs
+	r.biomes = r.biomes || biomes
+	s.received[pos] = r
+}
+
+// Received returns a bit mask of the indices of the sub chunks of the chunk at a position that were received, and
+// whether its biomes were received, as recorded using MarkReceived.
+func (s *CacheSource) Received(pos world.ChunkPos) (subs uint64, biomes bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.received[pos]
+	return r.subs, r.biomes
+}
+
+// SetBlockEntity stores the NBT data of the block entity at the position passed, replacing any block entity that was
+// previously stored there. Passing nil removes the block entity.
+func (s *CacheSource) SetBlockEntity(pos cube.Pos, data map[string]interface{}) {
+	chunkPos := world.ChunkPos{int32(pos[0] >> 4), int32(pos[2] >> 4)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.blockEntities[chunkPos]
+	if !ok {
+		if data == nil {
+			return
+		}
+		m = make(map[cube.Pos]map[string]interface{})
+		s.blockEntities[chunkPos] = m
+	}
+	if data == nil {
+		delete(m, pos)
+		return
+	}
+	m[pos] = data
+}
+
+// SetBlockEntities replaces all block entities stored in the chunk at the position passed with the NBT data passed,
+// which holds the world position of every block entity in its x, y and z fields.
+func (s *CacheSource) SetBlockEntities(pos world.ChunkPos, blockEntities []map[string]interface{}) {
+	m := make(map[cube.Pos]map[string]interface{}, len(blockEntities))
+	for _, data := range blockEntities {
+		x, okX := data["x"].(int32)
+		y, okY := data["y"].(int32)
+		z, okZ := data["z"].(int32)
+		if okX && okY && okZ {
+			m[cube.Pos{int(x), int(y), int(z)}] = data
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(m) == 0 {
+		delete(s.blockEntities, pos)
+		return
+	}
+	s.blockEntities[pos] = m
+}
+
+// BlockEntities returns the NBT data of all block entities stored in the chunk at the position passed.
+func (s *CacheSource) BlockEntities(pos world.ChunkPos) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.blockEntities[pos]
+	blockEntities := make([]map[string]interface{}, 0, len(m))
+	for _, data := range m {
+		blockEntities = append(blockEntities, data)
+	}
+	return blockEntities
+}
+
+// AddEntity stores the NBT data of the entity with the runtime ID passed, replacing any entity previously stored with
+// the same runtime ID. The NBT data must hold the position of the entity in its Pos field and its unique ID in its
+// UniqueID field.
+func (s *CacheSource) AddEntity(runtimeID uint64, data map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entities[runtimeID] = data
+}
+
+// MoveEntity updates the position of the entity with the runtime ID passed. Nothing happens if no entity with the
+// runtime ID is stored.
+func (s *CacheSource) MoveEntity(runtimeID uint64, pos mgl64.Vec3) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data, ok := s.entities[runtimeID]; ok {
+		data["Pos"] = []float32{float32(pos[0]), float32(pos[1]), float32(pos[2])}
+	}
+}
+
+// RemoveEntity removes the entity with the unique ID passed.
+func (s *CacheSource) RemoveEntity(uniqueID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for runtimeID, data := range s.entities {
+		if id, ok := data["UniqueID"].(int64); ok && id == uniqueID {
+			delete(s.entities, runtimeID)
+		}
+	}
+}
+
+// Entities returns the NBT data of all entities stored whose position is in the chunk at the position passed. The
+// NBT data returned is a copy, so that it is not changed when the entities move.
+func (s *CacheSource) Entities(pos world.ChunkPos) []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entities []map[string]interface{}
+	for _, data := range s.entities {
+		p, ok := data["Pos"].([]float32)
+		if !ok || len(p) != 3 {
+			continue
+		}
+		if (world.ChunkPos{int32(math.Floor(float64(p[0]))) >> 4, int32(math.Floor(float64(p[2]))) >> 4}) != pos {
+			continue
+		}
+		entity := make(map[string]interface{}, len(data))
+		for k, v := range data {
+			entity[k] = v
+		}
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
+// ClearEntities removes all entities from the cache, while keeping its chunks and block entities.
+func (s *CacheSource) ClearEntities() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entities = make(map[uint64]map[string]interface{})
+}
+
+// Clear removes all chunks, block entities and entities from the cache.
+func (s *CacheSource) Clear() {
+	s.mu.Lock()
+	old := s.chunks
+	s.chunks = make(map[world.ChunkPos]*chunk.Chunk)
+	s.blockEntities = make(map[world.ChunkPos]map[cube.Pos]map[string]interface{})
+	s.entities = make(map[uint64]map[string]interface{})
+	s.received = make(map[world.ChunkPos]receivedParts)
+	s.mu.Unlock()
+	for pos := range old {
+		s.notify(pos)
+	}
+}
+
+// notify calls all listeners of the cache with the position passed.
+func (s *CacheSource) notify(pos world.ChunkPos) {
+	s.mu.Lock()
+	listeners := s.listeners
+	s.mu.Unlock()
+	for _, f := range listeners {
+		f(pos)
+	}
+}
+
+// ProviderSource is a ChunkSource reading chunks from a saved world. Chunks are loaded lazily when they are first
+// requested, and only the most recently used chunks are kept in memory.
+type ProviderSource struct {
+	prov *mcdb.Provider
+	size int
+
+	mu        sync.Mutex
+	positions map[world.ChunkPos]struct{}
+	// lru holds the loaded chunks, with the most recently used chunk at the front. loaded maps the positions of the
+	// loaded chunks to their element in the list.
+	lru    *list.List
+	loaded map[world.ChunkPos]*list.Element
+}
+
+// providerEntry is a chunk loaded by a ProviderSource.
+type providerEntry struct {
+	pos world.ChunkPos
+	c   *chunk.Chunk
+}
+
+// NewProviderSource creates a ProviderSource reading chunks from the mcdb.Provider passed. At most size chunks are
+// kept in memory at once.
+func NewProviderSource(prov *mcdb.Provider, size int) (*ProviderSource, error) {
+	positions, err := prov.Positions()
+	if err != nil {
+		return nil, fmt.Errorf("error reading chunk positions: %w", err)
+	}
+	s := &ProviderSource{
+		prov:      prov,
+		size:      size,
+		positions: make(map[world.ChunkPos]struct{}, len(positions)),
+		lru:       list.New(),
+		loaded:    make(map[world.ChunkPos]*list.Element),
+	}
+	for _, pos := range positions {
+		s.positions[pos] = struct{}{}
+	}
+	return s, nil
+}
+
+// Chunk returns the chunk at the position passed, loading it from the provider if it is not currently loaded.
+func (s *ProviderSource) Chunk(pos world.ChunkPos) (*chunk.Chunk, bool) {
+	s.mu.Lock()
+	if _, ok := s.positions[pos]; !ok {
+		s.mu.Unlock()
+		return nil, false
+	}
+	if e, ok := s.loaded[pos]; ok {
+		s.lru.MoveToFront(e)
+		s.mu.Unlock()
+		return e.Value.(providerEntry).c, true
+	}
+	s.mu.Unlock()
+
+	c, ok, err := s.prov.LoadChunk(pos)
+	if err != nil || !ok {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.loaded[pos]; ok {
+		// The chunk was loaded by another goroutine in the meantime.
+		s.lru.MoveToFront(e)
+		return e.Value.(providerEntry).c, true
+	}
+	s.loaded[pos] = s.lru.PushFront(providerEntry{pos: pos, c: c})
+	for s.lru.Len() > s.size {
+		e := s.lru.Back()
+		s.lru.Remove(e)
+		delete(s.loaded, e.Value.(providerEntry).pos)
+	}
+	return c, true
+}
+
+// Positions returns the positions of all chunks in the saved world.
+func (s *ProviderSource) Positions() []world.ChunkPos {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	positions := make([]world.ChunkPos, 0, len(s.positions))
+	for pos := range s.positions {
+		positions = append(positions, pos)
+	}
+	return positions
+}
+
+// BlockEntities returns the NBT data of all block entities in the chunk at the position passed, as saved in the
+// world.
+func (s *ProviderSource) BlockEntities(pos world.ChunkPos) []map[string]interface{} {
+	blockEntities, _ := s.prov.LoadBlockNBT(pos)
+	return blockEntities
+}
+
+// Entities returns the NBT data of all entities in the chunk at the position passed, as saved in the world.
+func (s *ProviderSource) Entities(pos world.ChunkPos) []map[string]interface{} {
+	entities, _ := s.prov.LoadEntityNBT(pos)
+	return entities
+}
+
+// Listen does nothing, as the chunks of a saved world never change while they are rendered.
+func (s *ProviderSource) Listen(func(pos world.ChunkPos)) {}
+
+// lockNeighbourhood returns the chunk at the position passed and all chunks around it that exist in the ChunkSource.
+// All chunks returned are locked in a fixed order, so that multiple neighbourhoods may be locked at once without
+// deadlocking. The function returned unlocks all chunks again.
+func lockNeighbourhood(src ChunkSource, pos world.ChunkPos) (map[world.ChunkPos]*chunk.Chunk, func()) {
+	chunks := make(map[world.ChunkPos]*chunk.Chunk, 9)
+	var positions []world.ChunkPos
+	for x := int32(-1); x <= 1; x++ {
+		for z := int32(-1); z <= 1; z++ {
+			neighbour := world.ChunkPos{pos.X() + x, pos.Z() + z}
+			if c, ok := src.Chunk(neighbour); ok {
+				chunks[neighbour] = c
+				positions = append(positions, neighbour)
+			}
+		}
+	}
+	// The positions are ordered by their X and then by their Z coordinate, which is the order all neighbourhoods are
+	// locked in.
+	for _, p := range positions {
+		chunks[p].Lock()
+	}
+	return chunks, func() {
+		for _, p := range positions {
+			chunks[p].Unlock()
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package worldrenderer
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"image"
+	"image/draw"
+)
+
+const (
+	// regionChunks is the width and length in chunks of a region covered by a single tile.
+	regionChunks = 32
+	// tileSize is the width and height in pixels of a tile at its highest level of detail, where every block is
+	// rendered as a single pixel.
+	tileSize = regionChunks * 16
+	// mipLevels is the amount of levels of detail a tile has. Every level has half the width and height of the
+	// previous level.
+	mipLevels = 6
+)
+
+// tilePos holds the position of a tile, which is the position of the chunk in the top left corner of the tile divided
+// by regionChunks.
+type tilePos [2]int32
+
+// tilePosOf returns the position of the tile that contains the chunk position passed.
+func tilePosOf(pos world.ChunkPos) tilePos {
+	return tilePos{pos.X() >> 5, pos.Z() >> 5}
+}
+
+// tile is a square region of the world rendered at one pixel per block. The pixels are uploaded to *ebiten.Images,
+// together with downsampled versions of them used to draw the world while zoomed out.
+type tile struct {
+	// pixels holds the tile rendered at one pixel per block.
+	pixels *image.RGBA
+	// rendered holds for every chunk in the tile if it currently has pixels rendered in the tile.
+	rendered [regionChunks * regionChunks]bool
+	// count is the amount of chunks currently rendered in the tile.
+	count int
+	// dirty is true if the pixels changed since they were last uploaded to the mips.
+	dirty bool
+	// mips holds the images of all levels of detail of the tile, starting at the highest level.
+	mips [mipLevels]*ebiten.Image
+}
+
+// newTile creates a new, empty tile.
+func newTile() *tile {
+	return &tile{pixels: image.NewRGBA(image.Rect(0, 0, tileSize, tileSize))}
+}
+
+// chunkIndex returns the index of the chunk position passed in the rendered array of a tile.
+func chunkIndex(pos world.ChunkPos) int {
+	return int(pos.X()&(regionChunks-1)) | int(pos.Z()&(regionChunks-1))*regionChunks
+}
+
+// set draws the pixels of the chunk at the position passed into the tile. If img is nil, the area of the chunk is
+// cleared instead.
+func (t *tile) set(pos world.ChunkPos, img *image.RGBA) {
+	x, z := int(pos.X()&(regionChunks-1))<<4, int(pos.Z()&(regionChunks-1))<<4
+	area := image.Rect(x, z, x+16, z+16)
+
+	index := chunkIndex(pos)
+	if img == nil {
+		draw.Draw(t.pixels, area, image.Transparent, image.Point{}, draw.Src)
+		if t.rendered[index] {
+			t.rendered[index] = false
+			t.count--
+		}
+	} else {
+		draw.Draw(t.pixels, area, img, image.Point{}, draw.Src)
+		if !t.rendered[index] {
+			t.rendered[index] = true
+			t.count++
+		}
+	}
+	t.dirty = true
+}
+
+// upload uploads the pixels of the tile to its mips, downsampling them for every level of detail.
+func (t *tile) upload() {
+	pixels := t.pixels
+	for level := range t.mips {
+		if t.mips[level] == nil {
+			size := tileSize >> level
+			t.mips[level] = ebiten.NewImage(size, size)
+		}
+		if level != 0 {
+			pixels = downsample(pixels)
+		}
+		t.mips[level].ReplacePixels(pixels.Pix)
+	}
+	t.dirty = false
+}
+
+// dispose disposes all mips of the tile.
+func (t *tile) dispose() {
+	for _, img := range t.mips {
+		if img != nil {
+			img.Dispose()
+		}
+	}
+}
+
+// downsample returns a new image with half the width and height of the image passed, where every pixel is the
+// average of the four pixels it covers in the original image.
+func downsample(src *image.RGBA) *image.RGBA {
+	w, h := src.Bounds().Dx()/2, src.Bounds().Dy()/2
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i, j := src.PixOffset(x*2, y*2), src.PixOffset(x*2, y*2+1)
+			k := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				sum := uint16(src.Pix[i+c]) + uint16(src.Pix[i+4+c]) + uint16(src.Pix[j+c]) + uint16(src.Pix[j+4+c])
+				dst.Pix[k+c] = uint8(sum / 4)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,136 @@
+package worldrenderer
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"github.com/justtaldevelops/worldcompute/worldsearch"
+	"image"
+	"runtime"
+)
+
+// maxWorkers is the maximum amount of workers rendering dirty chunks in the background.
+const maxWorkers = 4
+
+// renderResult is the result of rendering a dirty chunk of a dimension. If the chunk no longer exists, img and iso
+// are both nil. The positions of all portal blocks in the chunk are held in portals.
+type renderResult struct {
+	dim     world.Dimension
+	img     *image.RGBA
+	iso     *isometricChunk
+	portals []cube.Pos
+}
+
+// startWorkers starts the workers that render dirty chunks in the background. One worker is started per CPU, up to
+// maxWorkers.
+func (r *Renderer) startWorkers() {
+	n := runtime.NumCPU()
+	if n > maxWorkers {
+		n = maxWorkers
+	}
+	for i := 0; i < n; i++ {
+		go r.work()
+	}
+}
+
+// work continuously takes dirty chunks from the queue and renders them, storing the results so that they can be
+// swapped into the tiles by the next update.
+func (r *Renderer) work() {
+	portals := worldsearch.NewSearcher(portalQuery)
+	for {
+		r.renderMu.Lock()
+		pos, ok := r.nextDirty()
+		for !ok {
+			r.renderCond.Wait()
+			pos, ok = r.nextDirty()
+		}
+		delete(r.dirty, pos)
+		r.inFlight[pos] = struct{}{}
+		ceiling, rot, isometric := r.ceiling(), r.rotation, r.isometric
+		light, threshold := r.light, r.lightThreshold
+		src, dim, p := r.src, r.viewDim, r.palette
+		r.renderMu.Unlock()
+
+		res := renderResult{dim: dim}
+		chunks, unlock := lockNeighbourhood(src, pos)
+		if c, ok := chunks[pos]; ok {
+			res.portals = portals.SearchChunk(pos, c)
+			if light != lightNone {
+				calculateLight(pos, chunks)
+			}
+			res.img = renderChunk(p, ceiling, light, threshold, pos, chunks)
+			if isometric {
+				res.iso = renderIsometricChunk(p, rot, ceiling, pos, chunks)
+			}
+		}
+		unlock()
+
+		r.renderMu.Lock()
+		r.results[pos] = res
+		delete(r.inFlight, pos)
+		if _, ok := r.dirty[pos]; ok {
+			// The chunk was marked dirty again while it was being rendered, so another worker may now pick it up.
+			r.renderCond.Broadcast()
+		}
+		r.renderMu.Unlock()
+	}
+}
+
+// nextDirty returns a dirty chunk position that is not currently being rendered by another worker. False is returned
+// if no such position exists. nextDirty must be called while holding the render mutex.
+func (r *Renderer) nextDirty() (world.ChunkPos, bool) {
+	for pos := range r.dirty {
+		if _, ok := r.inFlight[pos]; !ok {
+			return pos, true
+		}
+	}
+	return world.ChunkPos{}, false
+}
+
+// markDirty adds the chunk position passed to the queue of chunks to render and wakes up the workers. Marking a chunk
+// that is already queued has no effect. markDirty must be called while holding the render mutex.
+func (r *Renderer) markDirty(pos world.ChunkPos) {
+	if _, ok := r.dirty[pos]; ok {
+		return
+	}
+	r.dirty[pos] = struct{}{}
+	r.renderCond.Broadcast()
+}
+
+// applyResults swaps the chunks rendered by the workers into their tiles and uploads all tiles that changed. Tiles
+// that no longer hold any chunks are removed.
+func (r *Renderer) applyResults() {
+	r.renderMu.Lock()
+	defer r.renderMu.Unlock()
+	for pos, res := range r.results {
+		delete(r.results, pos)
+		r.storePortals(res.dim, pos, res.portals)
+		if res.dim != r.viewDim {
+			// The chunk was rendered before the view changed to another dimension. It was queued again when the view
+			// changed, so the result may be discarded.
+			continue
+		}
+		if r.isometric {
+			r.storeIsometric(pos, res.iso)
+		}
+
+		tp := tilePosOf(pos)
+		t, ok := r.tiles[tp]
+		if !ok {
+			if res.img == nil {
+				// Chunk doesn't exist and was never rendered, so there is nothing to clear.
+				continue
+			}
+			t = newTile()
+			r.tiles[tp] = t
+		}
+		t.set(pos, res.img)
+	}
+	for tp, t := range r.tiles {
+		if t.count == 0 {
+			t.dispose()
+			delete(r.tiles, tp)
+		} else if t.dirty {
+			t.upload()
+		}
+	}
+}
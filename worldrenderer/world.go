@@ -1,59 +1,40 @@
 package worldrenderer
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
 	"github.com/justtaldevelops/worldcompute/dragonfly/world"
-	"github.com/nfnt/resize"
 	"image"
-	"sync"
 )
 
-// renderWorld renders a world to *ebiten.Images.
-func renderWorld(scale int, chunkMu *sync.Mutex, chunks map[world.ChunkPos]*chunk.Chunk) map[world.ChunkPos]*ebiten.Image {
-	chunkMu.Lock()
-	defer chunkMu.Unlock()
-
-	var positions []world.ChunkPos
-	for pos := range chunks {
-		positions = append(positions, pos)
-	}
-
-	rendered := make(map[world.ChunkPos]*ebiten.Image)
-	for _, pos := range positions {
-		rendered[pos] = renderChunk(scale, pos, chunks)
-	}
-	return rendered
-}
-
-// renderChunk renders a new chunk image from the given chunk.
-func renderChunk(scale int, pos world.ChunkPos, chunks map[world.ChunkPos]*chunk.Chunk) *ebiten.Image {
+// renderChunk renders a new chunk image from the given chunk, with one pixel per block coloured using the Palette
+// passed. Each column is rendered using the highest block at or below the ceiling passed. The map passed must hold the
+// chunk and its neighbours to the north and west, which are used to shade the chunk. If a light mode is passed, the
+// light of the chunk must have been calculated already.
+func renderChunk(p *Palette, ceiling int16, light lightMode, threshold uint8, pos world.ChunkPos, chunks map[world.ChunkPos]*chunk.Chunk) *image.RGBA {
 	img := image.NewRGBA(image.Rectangle{Max: image.Point{X: 16, Y: 16}})
 	ch := chunks[pos]
 	for x := byte(0); x < 16; x++ {
 		for z := byte(0); z < 16; z++ {
-			y := ch.HighestBlock(x, z)
+			y := ch.HighestBlockBelow(x, z, ceiling)
 			name, properties, _ := chunk.RuntimeIDToState(ch.Block(x, y, z, 0))
 			rid, ok := chunk.StateToRuntimeID(name, properties)
 			if ok {
-				material := materials[rid]
-
-				northTargetX, northTargetZ := x, z-1
-				northWestTargetX, northWestTargetZ := x-1, z-1
+				northTargetX, northTargetZ := int(x), int(z)-1
+				northWestTargetX, northWestTargetZ := int(x)-1, int(z)-1
 
 				northChunk, northExists := chunks[world.ChunkPos{
-					int32(northTargetX)>>4 + pos.X(),
-					int32(northTargetZ)>>4 + pos.Z(),
+					int32(northTargetX>>4) + pos.X(),
+					int32(northTargetZ>>4) + pos.Z(),
 				}]
 				northWestChunk, northWestExists := chunks[world.ChunkPos{
-					int32(northWestTargetX)>>4 + pos.X(),
-					int32(northWestTargetZ)>>4 + pos.Z(),
+					int32(northWestTargetX>>4) + pos.X(),
+					int32(northWestTargetZ>>4) + pos.Z(),
 				}]
 
 				modifier := 0.8627
 				if northExists && northWestExists {
-					northY := northChunk.HighestBlock(northTargetX, northTargetZ)
-					northWestY := northWestChunk.HighestBlock(northWestTargetX, northWestTargetZ)
+					northY := northChunk.HighestBlockBelow(uint8(northTargetX&15), uint8(northTargetZ&15), ceiling)
+					northWestY := northWestChunk.HighestBlockBelow(uint8(northWestTargetX&15), uint8(northWestTargetZ&15), ceiling)
 					if northY > y && northWestY <= y {
 						modifier = 0.7058
 					} else if northY > y && northWestY > y {
@@ -63,16 +44,25 @@ func renderChunk(scale int, pos world.ChunkPos, chunks map[world.ChunkPos]*chunk
 					}
 				}
 
-				colour := materialColours[material]
-				if material > 0 {
+				colour, known := p.colour(rid)
+				if known || materials[rid] > 0 {
 					colour.R = uint8(float64(colour.R) * modifier)
 					colour.G = uint8(float64(colour.G) * modifier)
 					colour.B = uint8(float64(colour.B) * modifier)
 				}
+				switch light {
+				case lightSpawnable:
+					if blockLight, _ := lightAbove(ch, x, y, z); blockLight < threshold && spawnable(ch, x, y, z) {
+						colour = mix(colour, darkSpotColour)
+					}
+				case lightSky:
+					_, skyLight := lightAbove(ch, x, y, z)
+					colour = shade(colour, 0.25+0.75*float64(skyLight)/15)
+				}
 
 				img.Set(int(x), int(z), colour)
 			}
 		}
 	}
-	return ebiten.NewImageFromImage(resize.Resize(uint(scale*16), uint(scale*16), img, resize.NearestNeighbor))
+	return img
 }
@@ -0,0 +1,90 @@
+package worldsearch
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Query is a search query matching block states. A query consists of a block name, which may contain '*' wildcards,
+// optionally followed by a list of properties that the block state must have, such as
+// minecraft:stone_block_slab[top_slot_bit=true]. Block names without a namespace are assumed to be in the minecraft
+// namespace.
+type Query struct {
+	name       string
+	properties map[string]string
+}
+
+// ParseQuery parses a Query from the string passed. An error is returned if the string is not a valid query.
+func ParseQuery(s string) (Query, error) {
+	s = strings.TrimSpace(s)
+	q := Query{name: s}
+	if i := strings.IndexByte(s, '['); i != -1 {
+		if !strings.HasSuffix(s, "]") {
+			return q, fmt.Errorf("query %q has unterminated properties", s)
+		}
+		q.name, q.properties = s[:i], make(map[string]string)
+		for _, pair := range strings.Split(s[i+1:len(s)-1], ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return q, fmt.Errorf("query %q has property %q without value", s, pair)
+			}
+			q.properties[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	if q.name == "" {
+		return q, fmt.Errorf("query %q has no block name", s)
+	}
+	if !strings.Contains(q.name, ":") {
+		q.name = "minecraft:" + q.name
+	}
+	if _, err := path.Match(q.name, ""); err != nil {
+		return q, fmt.Errorf("query %q has malformed block name: %w", s, err)
+	}
+	return q, nil
+}
+
+// Matches checks if the block state with the name and properties passed matches the query.
+func (q Query) Matches(name string, properties map[string]interface{}) bool {
+	if ok, _ := path.Match(q.name, name); !ok {
+		return false
+	}
+	for k, want := range q.properties {
+		v, ok := properties[k]
+		if !ok || !valueMatches(v, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// valueMatches checks if the property value passed matches the value wanted by a query. Boolean properties, such as
+// top_slot_bit, are stored as bytes, so they match true and false as well as 1 and 0.
+func valueMatches(v interface{}, want string) bool {
+	if b, ok := v.(uint8); ok {
+		switch want {
+		case "true":
+			return b == 1
+		case "false":
+			return b == 0
+		}
+	}
+	return fmt.Sprint(v) == want
+}
+
+// String returns the query in the format it is parsed from.
+func (q Query) String() string {
+	if len(q.properties) == 0 {
+		return q.name
+	}
+	pairs := make([]string, 0, len(q.properties))
+	for k, v := range q.properties {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return q.name + "[" + strings.Join(pairs, ",") + "]"
+}
@@ -0,0 +1,107 @@
+package worldsearch
+
+import (
+	"github.com/justtaldevelops/worldcompute/dragonfly/chunk"
+	"github.com/justtaldevelops/worldcompute/dragonfly/cube"
+	"github.com/justtaldevelops/worldcompute/dragonfly/world"
+	"sort"
+)
+
+// Source is a source of chunks that may be searched. Chunks returned by a Source are locked using their embedded mutex
+// while they are searched.
+type Source interface {
+	// Chunk returns the chunk at the position passed. False is returned if the source has no chunk at the position.
+	Chunk(pos world.ChunkPos) (*chunk.Chunk, bool)
+	// Positions returns the positions of all chunks in the source.
+	Positions() []world.ChunkPos
+}
+
+// Searcher searches chunks for blocks matching a Query. It remembers for every runtime ID if it matches the query, so
+// that block states are only looked up once.
+type Searcher struct {
+	q       Query
+	matches map[uint32]bool
+}
+
+// NewSearcher creates a new Searcher for the Query passed.
+func NewSearcher(q Query) *Searcher {
+	return &Searcher{q: q, matches: make(map[uint32]bool)}
+}
+
+// Search searches all chunks in the Source passed and returns the positions of all blocks matching the query. The
+// chunks are searched in order of their X and Z coordinates.
+func (s *Searcher) Search(src Source) []cube.Pos {
+	positions := src.Positions()
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].X() != positions[j].X() {
+			return positions[i].X() < positions[j].X()
+		}
+		return positions[i].Z() < positions[j].Z()
+	})
+
+	var found []cube.Pos
+	for _, pos := range positions {
+		c, ok := src.Chunk(pos)
+		if !ok {
+			continue
+		}
+		c.Lock()
+		found = append(found, s.SearchChunk(pos, c)...)
+		c.Unlock()
+	}
+	return found
+}
+
+// SearchChunk returns the positions of all blocks in the chunk passed that match the query on any layer, each position
+// being returned once. Sub chunks of which none of the palettes hold a matching block state are skipped entirely.
+func (s *Searcher) SearchChunk(pos world.ChunkPos, c *chunk.Chunk) []cube.Pos {
+	var found []cube.Pos
+	baseX, baseZ := int(pos.X())<<4, int(pos.Z())<<4
+	for index, sub := range c.Sub() {
+		baseY := int(c.SubY(int16(index)))
+		var layers []*chunk.PalettedStorage
+		for _, storage := range sub.Layers() {
+			if s.paletteMatches(storage.Palette()) {
+				layers = append(layers, storage)
+			}
+		}
+		if len(layers) == 0 {
+			continue
+		}
+		for x := byte(0); x < 16; x++ {
+			for z := byte(0); z < 16; z++ {
+				for y := byte(0); y < 16; y++ {
+					for _, storage := range layers {
+						if s.match(storage.At(x, y, z)) {
+							// A position is only reported once, even if the blocks on multiple layers match.
+							found = append(found, cube.Pos{baseX + int(x), baseY + int(y), baseZ + int(z)})
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// paletteMatches checks if any of the block states in the palette passed match the query.
+func (s *Searcher) paletteMatches(p *chunk.Palette) bool {
+	for i := 0; i < p.Len(); i++ {
+		if s.match(p.Value(uint16(i))) {
+			return true
+		}
+	}
+	return false
+}
+
+// match checks if the block state with the runtime ID passed matches the query.
+func (s *Searcher) match(rid uint32) bool {
+	if m, ok := s.matches[rid]; ok {
+		return m
+	}
+	name, properties, ok := chunk.RuntimeIDToState(rid)
+	m := ok && s.q.Matches(name, properties)
+	s.matches[rid] = m
+	return m
+}